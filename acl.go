@@ -0,0 +1,507 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const clusterResourceName = "kafka-cluster"
+
+type aclBinding struct {
+	ResourceType string `json:"resourceType" yaml:"resourceType"`
+	Name         string `json:"name" yaml:"name"`
+	PatternType  string `json:"patternType,omitempty" yaml:"patternType,omitempty"`
+	Principal    string `json:"principal" yaml:"principal"`
+	Host         string `json:"host,omitempty" yaml:"host,omitempty"`
+	Operation    string `json:"operation" yaml:"operation"`
+	Permission   string `json:"permission,omitempty" yaml:"permission,omitempty"`
+}
+
+type aclSpec struct {
+	Acls []*aclBinding `json:"acls" yaml:"acls"`
+}
+
+func newAclBinding(binding *kafkaadmin.AclBinding) *aclBinding {
+	return &aclBinding{
+		ResourceType: strings.ToLower(binding.ResourceType.String()),
+		Name:         binding.ResourceName,
+		PatternType:  strings.ToLower(binding.ResourcePatternType.String()),
+		Principal:    binding.Principal,
+		Host:         binding.Host,
+		Operation:    strings.ToLower(binding.Operation.String()),
+		Permission:   strings.ToLower(binding.PermissionType.String())}
+}
+
+func (a *aclBinding) String() string {
+	return fmt.Sprintf("%s %s@%s %s on %s %s (%s)", a.Permission, a.Principal, a.Host, a.Operation, a.ResourceType, a.Name, a.PatternType)
+}
+
+// parseAclEnum parses an ACL enum case-insensitively ignoring dashes and underscores (transactional-id),
+// an empty value is "any".
+func parseAclEnum(value string, name string, target interface{ UnmarshalText([]byte) error }) error {
+	if len(value) == 0 {
+		value = "any"
+	}
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(value)
+	if err := target.UnmarshalText([]byte(normalized)); err != nil {
+		return fmt.Errorf("invalid %s %s", name, value)
+	}
+	return nil
+}
+
+func (a *aclBinding) toBinding() (*kafkaadmin.AclBinding, error) {
+	binding := &kafkaadmin.AclBinding{}
+	if len(a.PatternType) == 0 {
+		a.PatternType = "literal"
+	}
+	if len(a.Host) == 0 {
+		a.Host = "*"
+	}
+	if len(a.Permission) == 0 {
+		a.Permission = "allow"
+	}
+	if len(a.Principal) == 0 {
+		return nil, fmt.Errorf("principal expected")
+	}
+	if len(a.ResourceType) == 0 || len(a.Operation) == 0 {
+		return nil, fmt.Errorf("resource type and operation expected for %s", a.Principal)
+	}
+	err := parseAclEnum(a.ResourceType, "resource type", &binding.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+	if binding.ResourceType == sarama.AclResourceCluster && len(a.Name) == 0 {
+		a.Name = clusterResourceName
+	}
+	if len(a.Name) == 0 {
+		return nil, fmt.Errorf("resource name expected for %s %s", a.ResourceType, a.Principal)
+	}
+	err = parseAclEnum(a.PatternType, "pattern type", &binding.ResourcePatternType)
+	if err != nil {
+		return nil, err
+	}
+	err = parseAclEnum(a.Operation, "operation", &binding.Operation)
+	if err != nil {
+		return nil, err
+	}
+	err = parseAclEnum(a.Permission, "permission", &binding.PermissionType)
+	if err != nil {
+		return nil, err
+	}
+	if binding.ResourceType == sarama.AclResourceAny || binding.Operation == sarama.AclOperationAny ||
+		binding.PermissionType == sarama.AclPermissionAny {
+		return nil, fmt.Errorf("resource type, operation and permission cannot be any")
+	}
+	if binding.ResourcePatternType != sarama.AclPatternLiteral && binding.ResourcePatternType != sarama.AclPatternPrefixed {
+		return nil, fmt.Errorf("pattern type has to be literal or prefixed")
+	}
+	binding.ResourceName = a.Name
+	binding.Principal = a.Principal
+	binding.Host = a.Host
+	return binding, nil
+}
+
+type aclFlags struct {
+	resourceType string
+	name         string
+	patternType  string
+	principal    string
+	host         string
+	operations   []string
+	permission   string
+}
+
+func (a *aclFlags) addFlags(flags *pflag.FlagSet, isFilter bool) {
+	flags.StringVarP(&a.resourceType, "resource-type", "r", "", "resource type: topic, group, cluster, transactional-id or delegation-token")
+	flags.StringVarP(&a.name, "name", "n", "", "resource name")
+	flags.StringVarP(&a.principal, "principal", "p", "", "principal, e.g. User:alice")
+	flags.StringSliceVar(&a.operations, "operation", nil, "operations: all, read, write, create, delete, alter, describe, clusteraction, describeconfigs, alterconfigs or idempotentwrite")
+	if isFilter {
+		flags.StringVar(&a.patternType, "pattern", "", "pattern type: literal, prefixed, match (bindings applying to the name) or any")
+		flags.StringVar(&a.host, "host", "", "host")
+		flags.StringVar(&a.permission, "permission", "", "permission: allow or deny")
+	} else {
+		flags.StringVar(&a.patternType, "pattern", "literal", "pattern type: literal or prefixed")
+		flags.StringVar(&a.host, "host", "*", "host")
+		flags.StringVar(&a.permission, "permission", "allow", "permission: allow or deny")
+	}
+}
+
+func (a *aclFlags) filters() ([]*sarama.AclFilter, error) {
+	operations := a.operations
+	if len(operations) == 0 {
+		operations = []string{"any"}
+	}
+	var result []*sarama.AclFilter
+	for _, operation := range operations {
+		filter := &sarama.AclFilter{Version: 1}
+		err := parseAclEnum(a.resourceType, "resource type", &filter.ResourceType)
+		if err != nil {
+			return nil, err
+		}
+		err = parseAclEnum(a.patternType, "pattern type", &filter.ResourcePatternTypeFilter)
+		if err != nil {
+			return nil, err
+		}
+		err = parseAclEnum(operation, "operation", &filter.Operation)
+		if err != nil {
+			return nil, err
+		}
+		err = parseAclEnum(a.permission, "permission", &filter.PermissionType)
+		if err != nil {
+			return nil, err
+		}
+		if len(a.name) > 0 {
+			filter.ResourceName = &a.name
+		}
+		if len(a.principal) > 0 {
+			filter.Principal = &a.principal
+		}
+		if len(a.host) > 0 {
+			filter.Host = &a.host
+		}
+		result = append(result, filter)
+	}
+	return result, nil
+}
+
+func (a *aclFlags) bindings() ([]*kafkaadmin.AclBinding, error) {
+	if len(a.operations) == 0 {
+		return nil, fmt.Errorf("at least one --operation expected")
+	}
+	var result []*kafkaadmin.AclBinding
+	for _, operation := range a.operations {
+		binding, err := (&aclBinding{
+			ResourceType: a.resourceType,
+			Name:         a.name,
+			PatternType:  a.patternType,
+			Principal:    a.principal,
+			Host:         a.host,
+			Operation:    operation,
+			Permission:   a.permission}).toBinding()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, binding)
+	}
+	return result, nil
+}
+
+func describeAcls(client sarama.Client, filters []*sarama.AclFilter) ([]*aclBinding, error) {
+	seen := make(map[string]bool)
+	var result []*aclBinding
+	for _, filter := range filters {
+		bindings, err := kafkaadmin.DescribeAcls(client, *filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, binding := range bindings {
+			item := newAclBinding(binding)
+			if !seen[item.String()] {
+				seen[item.String()] = true
+				result = append(result, item)
+			}
+		}
+	}
+	sortAclBindings(result)
+	return result, nil
+}
+
+func sortAclBindings(bindings []*aclBinding) {
+	sort.Slice(bindings, func(i, j int) bool {
+		left := []string{bindings[i].ResourceType, bindings[i].Name, bindings[i].PatternType, bindings[i].Principal,
+			bindings[i].Host, bindings[i].Operation, bindings[i].Permission}
+		right := []string{bindings[j].ResourceType, bindings[j].Name, bindings[j].PatternType, bindings[j].Principal,
+			bindings[j].Host, bindings[j].Operation, bindings[j].Permission}
+		for k := range left {
+			if left[k] != right[k] {
+				return left[k] < right[k]
+			}
+		}
+		return false
+	})
+}
+
+func writeAclBindings(format string, bindings []*aclBinding) error {
+	if format != "text" {
+		return writeStructured(os.Stdout, format, &aclSpec{Acls: bindings})
+	}
+	var rows [][]string
+	for _, binding := range bindings {
+		rows = append(rows, []string{binding.ResourceType, binding.Name, binding.PatternType, binding.Principal,
+			binding.Host, binding.Operation, binding.Permission})
+	}
+	return writeTable(os.Stdout, []string{"resource", "name", "pattern", "principal", "host", "operation", "permission"}, rows)
+}
+
+type aclListCmdType struct {
+	flags  aclFlags
+	output string
+}
+
+func (a *aclListCmdType) Run(cmd *cobra.Command, args []string) error {
+	filters, err := a.flags.filters()
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	bindings, err := describeAcls(client, filters)
+	if err != nil {
+		return err
+	}
+	return writeAclBindings(a.output, bindings)
+}
+
+type aclAddCmdType struct {
+	flags aclFlags
+}
+
+func (a *aclAddCmdType) Run(cmd *cobra.Command, args []string) error {
+	bindings, err := a.flags.bindings()
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	addAudit := newAudit(client)
+	for _, binding := range bindings {
+		addAudit.describe(auditAcl, newAclBinding(binding).String(), "added")
+	}
+	err = addAudit.finish(kafkaadmin.CreateAcls(client, bindings))
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		fmt.Println("added", newAclBinding(binding))
+	}
+	return nil
+}
+
+type aclRemoveCmdType struct {
+	flags       aclFlags
+	isAssumeYes bool
+}
+
+func (a *aclRemoveCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(a.flags.resourceType) == 0 && len(a.flags.name) == 0 && len(a.flags.principal) == 0 {
+		return fmt.Errorf("at least one of --resource-type, --name or --principal expected")
+	}
+	filters, err := a.flags.filters()
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	matching, err := describeAcls(client, filters)
+	if err != nil {
+		return err
+	}
+	if len(matching) == 0 {
+		fmt.Println("no matching acls")
+		return nil
+	}
+	for _, binding := range matching {
+		fmt.Println("-", binding)
+	}
+	if !a.isAssumeYes && !confirmTyped(fmt.Sprintf("%d acls will be removed", len(matching)), strconv.Itoa(len(matching))) {
+		return fmt.Errorf("aborted")
+	}
+	removeAudit := newAudit(client)
+	for _, binding := range matching {
+		removeAudit.describe(auditAcl, binding.String(), "removed")
+	}
+	deleted, err := kafkaadmin.DeleteAcls(client, filters)
+	fmt.Printf("%d acls removed\n", len(deleted))
+	return removeAudit.finish(err)
+}
+
+func readAclSpec(fileName string, format string) ([]*kafkaadmin.AclBinding, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var spec aclSpec
+	switch detectFormat(format, fileName) {
+	case "json":
+		err = json.Unmarshal(data, &spec)
+	case "yaml":
+		err = yaml.Unmarshal(data, &spec)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []*kafkaadmin.AclBinding
+	for _, item := range spec.Acls {
+		binding, err := item.toBinding()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, binding)
+	}
+	return result, nil
+}
+
+type aclApplyCmdType struct {
+	fileName    string
+	format      string
+	shouldPrune bool
+	isDryRun    bool
+	isAssumeYes bool
+}
+
+func (a *aclApplyCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(a.fileName) == 0 {
+		return fmt.Errorf("spec file expected (-f)")
+	}
+	desired, err := readAclSpec(a.fileName, a.format)
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	current, err := kafkaadmin.DescribeAcls(client, sarama.AclFilter{
+		ResourceType:              sarama.AclResourceAny,
+		ResourcePatternTypeFilter: sarama.AclPatternAny,
+		Operation:                 sarama.AclOperationAny,
+		PermissionType:            sarama.AclPermissionAny})
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, binding := range current {
+		existing[newAclBinding(binding).String()] = true
+	}
+	wanted := make(map[string]bool)
+	var creations []*kafkaadmin.AclBinding
+	for _, binding := range desired {
+		key := newAclBinding(binding).String()
+		if wanted[key] {
+			continue
+		}
+		wanted[key] = true
+		if !existing[key] {
+			creations = append(creations, binding)
+		}
+	}
+	var deletions []*kafkaadmin.AclBinding
+	for _, binding := range current {
+		if !wanted[newAclBinding(binding).String()] {
+			deletions = append(deletions, binding)
+		}
+	}
+	for _, binding := range creations {
+		fmt.Println("+", newAclBinding(binding))
+	}
+	if a.shouldPrune {
+		for _, binding := range deletions {
+			fmt.Println("-", newAclBinding(binding))
+		}
+	} else if len(deletions) > 0 {
+		fmt.Printf("%d acls are not in the spec, use --prune to remove them\n", len(deletions))
+	}
+	if !a.shouldPrune {
+		deletions = nil
+	}
+	if len(creations) == 0 && len(deletions) == 0 {
+		fmt.Println("acls are up to date")
+		return nil
+	}
+	if a.isDryRun {
+		return nil
+	}
+	if len(deletions) > 0 && !a.isAssumeYes &&
+		!confirmTyped(fmt.Sprintf("%d acls will be removed", len(deletions)), strconv.Itoa(len(deletions))) {
+		return fmt.Errorf("aborted")
+	}
+	applyAudit := newAudit(client)
+	for _, binding := range creations {
+		applyAudit.describe(auditAcl, newAclBinding(binding).String(), "added")
+	}
+	for _, binding := range deletions {
+		applyAudit.describe(auditAcl, newAclBinding(binding).String(), "removed")
+	}
+	return applyAudit.finish(a.apply(client, creations, deletions))
+}
+
+func (a *aclApplyCmdType) apply(client sarama.Client, creations []*kafkaadmin.AclBinding, deletions []*kafkaadmin.AclBinding) error {
+	if len(creations) > 0 {
+		err := kafkaadmin.CreateAcls(client, creations)
+		if err != nil {
+			return err
+		}
+	}
+	if len(deletions) > 0 {
+		var filters []*sarama.AclFilter
+		for _, binding := range deletions {
+			filters = append(filters, kafkaadmin.BindingFilter(binding))
+		}
+		_, err := kafkaadmin.DeleteAcls(client, filters)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d acls added, %d removed\n", len(creations), len(deletions))
+	return nil
+}
+
+var aclListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list acls matching the filter flags (all by default)"}
+
+var aclAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "add acls, one per --operation"}
+
+var aclRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "remove acls matching the filter flags"}
+
+var aclApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "add acls missing from the cluster according to a YAML/JSON spec, --prune removes the ones not in the spec"}
+
+func init() {
+	var listRunner aclListCmdType
+	aclListCmd.RunE = listRunner.Run
+	listRunner.flags.addFlags(aclListCmd.Flags(), true)
+	aclListCmd.Flags().StringVarP(&listRunner.output, "output", "o", "text", "output format: text, json or yaml")
+	var addRunner aclAddCmdType
+	aclAddCmd.RunE = addRunner.Run
+	addRunner.flags.addFlags(aclAddCmd.Flags(), false)
+	var removeRunner aclRemoveCmdType
+	aclRemoveCmd.RunE = removeRunner.Run
+	removeRunner.flags.addFlags(aclRemoveCmd.Flags(), true)
+	aclRemoveCmd.Flags().BoolVarP(&removeRunner.isAssumeYes, "yes", "y", false, "do not ask for confirmation")
+	var applyRunner aclApplyCmdType
+	aclApplyCmd.RunE = applyRunner.Run
+	flags := aclApplyCmd.Flags()
+	flags.StringVarP(&applyRunner.fileName, "file", "f", "", "acls spec file")
+	flags.StringVarP(&applyRunner.format, "format", "F", "", "spec format: yaml or json (detected by file extension if not set)")
+	flags.BoolVar(&applyRunner.shouldPrune, "prune", false, "remove acls missing from the spec")
+	flags.BoolVar(&applyRunner.isDryRun, "dry-run", false, "only show the difference")
+	flags.BoolVarP(&applyRunner.isAssumeYes, "yes", "y", false, "do not ask for confirmation")
+}
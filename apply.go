@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+type topicSpec struct {
+	Name              string            `json:"name" yaml:"name"`
+	Partitions        int32             `json:"partitions,omitempty" yaml:"partitions,omitempty"`
+	ReplicationFactor int16             `json:"replicationFactor,omitempty" yaml:"replicationFactor,omitempty"`
+	Configs           map[string]string `json:"configs,omitempty" yaml:"configs,omitempty"`
+	ReplicaAssignment map[int32][]int32 `json:"replicaAssignment,omitempty" yaml:"replicaAssignment,omitempty"`
+}
+
+type topicsSpec struct {
+	Topics []*topicSpec `json:"topics" yaml:"topics"`
+}
+
+func readTopicsSpec(fileName string, format string) (*topicsSpec, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var spec topicsSpec
+	switch detectFormat(format, fileName) {
+	case "json":
+		err = json.Unmarshal(data, &spec)
+	case "yaml":
+		err = yaml.Unmarshal(data, &spec)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, topic := range spec.Topics {
+		if len(topic.Name) == 0 {
+			return nil, fmt.Errorf("topic without a name in %s", fileName)
+		}
+		if names[topic.Name] {
+			return nil, fmt.Errorf("topic %s is specified twice", topic.Name)
+		}
+		names[topic.Name] = true
+	}
+	return &spec, nil
+}
+
+type topicPlan struct {
+	name              string
+	spec              *topicSpec
+	shouldCreate      bool
+	shouldDelete      bool
+	currentPartitions int32
+	partitions        int32
+	newAssignment     [][]int32
+	currentConfigs    kafkaadmin.RawConfig
+	changes           kafkaadmin.ConfigChanges
+	warnings          []string
+}
+
+func (p *topicPlan) hasChanges() bool {
+	return p.shouldCreate || p.shouldDelete || p.partitions > p.currentPartitions || len(p.changes) > 0
+}
+
+func (p *topicPlan) hasDrift() bool {
+	return p.hasChanges() || len(p.warnings) > 0
+}
+
+func isInternalTopic(topic *sarama.TopicMetadata) bool {
+	return topic.IsInternal || strings.HasPrefix(topic.Name, "__")
+}
+
+// makeSpecChanges manages only the configs set on the topic itself, configs absent from the spec are reverted
+// to defaults.
+func makeSpecChanges(spec *topicSpec, current kafkaadmin.RawConfig) kafkaadmin.ConfigChanges {
+	set := make(map[string]string)
+	for key, value := range spec.Configs {
+		config, ok := current[key]
+		if !ok || config.Source != sarama.SourceTopic || config.Value != value {
+			set[key] = value
+		}
+	}
+	var unset []string
+	for key, config := range current {
+		if _, ok := spec.Configs[key]; !ok && config.Source == sarama.SourceTopic {
+			unset = append(unset, key)
+		}
+	}
+	return kafkaadmin.SetConfigChanges(set, unset)
+}
+
+func buildTopicsPlan(client sarama.Client, spec *topicsSpec, pruneCandidates []string) ([]*topicPlan, error) {
+	metadata, err := kafkaadmin.GetMetadata(client)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*sarama.TopicMetadata)
+	for _, topic := range metadata.Topics {
+		if topic.Err == sarama.ErrNoError {
+			existing[topic.Name] = topic
+		}
+	}
+	var existingNames []string
+	for _, topic := range spec.Topics {
+		if _, ok := existing[topic.Name]; ok {
+			existingNames = append(existingNames, topic.Name)
+		}
+	}
+	var configs kafkaadmin.TopicsConfigs
+	if len(existingNames) > 0 {
+		configs, err = kafkaadmin.GetTopicConfigs(client, existingNames...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var result []*topicPlan
+	managed := make(map[string]bool)
+	for _, topic := range spec.Topics {
+		managed[topic.Name] = true
+		plan := &topicPlan{name: topic.Name, spec: topic}
+		result = append(result, plan)
+		current, ok := existing[topic.Name]
+		if !ok {
+			plan.shouldCreate = true
+			plan.partitions = topic.Partitions
+			if len(topic.ReplicaAssignment) > 0 {
+				plan.partitions = int32(len(topic.ReplicaAssignment))
+			}
+			continue
+		}
+		plan.currentPartitions = int32(len(current.Partitions))
+		plan.partitions = plan.currentPartitions
+		if topic.Partitions > plan.currentPartitions {
+			plan.partitions = topic.Partitions
+			for partition := plan.currentPartitions; partition < topic.Partitions; partition++ {
+				replicas, ok := topic.ReplicaAssignment[partition]
+				if !ok {
+					plan.newAssignment = nil
+					break
+				}
+				plan.newAssignment = append(plan.newAssignment, replicas)
+			}
+		} else if topic.Partitions > 0 && topic.Partitions < plan.currentPartitions {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("has %d partitions, the number of partitions cannot be decreased to %d", plan.currentPartitions, topic.Partitions))
+		}
+		if topic.ReplicationFactor > 0 && len(current.Partitions) > 0 && int(topic.ReplicationFactor) != len(current.Partitions[0].Replicas) {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("replication factor is %d instead of %d, not changed", len(current.Partitions[0].Replicas), topic.ReplicationFactor))
+		}
+		plan.currentConfigs = configs[topic.Name]
+		plan.changes = makeSpecChanges(topic, plan.currentConfigs)
+	}
+	for _, name := range pruneCandidates {
+		if _, ok := existing[name]; ok && !managed[name] {
+			result = append(result, &topicPlan{name: name, shouldDelete: true})
+		}
+	}
+	return result, nil
+}
+
+func printTopicsPlan(plans []*topicPlan) {
+	changed := 0
+	for _, plan := range plans {
+		if !plan.hasDrift() {
+			continue
+		}
+		changed++
+		switch {
+		case plan.shouldCreate:
+			fmt.Printf("+ %s: create", plan.name)
+			if plan.partitions > 0 {
+				fmt.Printf(", %d partitions", plan.partitions)
+			}
+			if plan.spec.ReplicationFactor > 0 {
+				fmt.Printf(", replication factor %d", plan.spec.ReplicationFactor)
+			}
+			fmt.Println("")
+			for _, key := range sortedKeys(plan.spec.Configs) {
+				fmt.Printf("\t%s=%s\n", key, plan.spec.Configs[key])
+			}
+		case plan.shouldDelete:
+			fmt.Printf("- %s: delete\n", plan.name)
+		default:
+			fmt.Printf("~ %s\n", plan.name)
+			if plan.partitions > plan.currentPartitions {
+				fmt.Printf("\tpartitions: %d -> %d\n", plan.currentPartitions, plan.partitions)
+			}
+			if len(plan.changes) > 0 {
+				printConfigDiff("\t", plan.currentConfigs, plan.changes)
+			}
+		}
+		for _, warning := range plan.warnings {
+			fmt.Printf("\t! %s\n", warning)
+		}
+	}
+	if changed == 0 {
+		fmt.Println("no changes, topics match the spec")
+	}
+}
+
+func applyTopicsPlan(client sarama.Client, plans []*topicPlan) error {
+	var failed bool
+	var report = func(action string, err error) {
+		if err == nil {
+			return
+		}
+		failed = true
+		var topicErrors kafkaadmin.TopicErrors
+		if errors.As(err, &topicErrors) {
+			fmt.Printf("Errors while %s\n%v\n", action, topicErrors)
+		} else {
+			fmt.Printf("Error while %s: %v\n", action, err)
+		}
+	}
+	details := make(map[string]*sarama.TopicDetail)
+	partitions := make(map[string]*sarama.TopicPartition)
+	var deletes []string
+	for _, plan := range plans {
+		switch {
+		case plan.shouldCreate:
+			detail := &sarama.TopicDetail{
+				NumPartitions:     -1,
+				ReplicationFactor: -1,
+				ReplicaAssignment: plan.spec.ReplicaAssignment,
+				ConfigEntries:     make(map[string]*string)}
+			if len(plan.spec.ReplicaAssignment) == 0 {
+				detail.ReplicaAssignment = make(map[int32][]int32)
+				if plan.spec.Partitions > 0 {
+					detail.NumPartitions = plan.spec.Partitions
+				}
+				if plan.spec.ReplicationFactor > 0 {
+					detail.ReplicationFactor = plan.spec.ReplicationFactor
+				}
+			}
+			for key, value := range plan.spec.Configs {
+				pvalue := new(string)
+				*pvalue = value
+				detail.ConfigEntries[key] = pvalue
+			}
+			details[plan.name] = detail
+		case plan.shouldDelete:
+			deletes = append(deletes, plan.name)
+		case plan.partitions > plan.currentPartitions:
+			partitions[plan.name] = &sarama.TopicPartition{Count: plan.partitions, Assignment: plan.newAssignment}
+		}
+	}
+	if len(details) > 0 {
+		report("creating topics", kafkaadmin.CreateTopicDetails(client, details, false))
+	}
+	if len(partitions) > 0 {
+		report("adding partitions", kafkaadmin.CreateTopicPartitions(client, partitions, false))
+	}
+	for _, plan := range plans {
+		if !plan.shouldCreate && !plan.shouldDelete && len(plan.changes) > 0 {
+			report("updating configs of "+plan.name, kafkaadmin.ModifyTopicConfig(client, plan.changes, false, plan.name))
+		}
+	}
+	if len(deletes) > 0 {
+		report("deleting topics", kafkaadmin.DeleteTopics(client, deletes...))
+	}
+	if failed {
+		return fmt.Errorf("some changes were not applied")
+	}
+	return nil
+}
+
+type applyCmdType struct {
+	fileName    string
+	format      string
+	shouldPrune bool
+	selector    topicSelector
+	isPlanOnly  bool
+	guard       destructiveGuard
+}
+
+func (a *applyCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(a.fileName) == 0 {
+		return fmt.Errorf("spec file expected (-f)")
+	}
+	if a.shouldPrune && !a.selector.isPositive(args) {
+		return fmt.Errorf("--prune requires --regex, topic patterns or --where, --exclude alone would select every other topic")
+	}
+	if !a.shouldPrune && len(args) > 0 {
+		return fmt.Errorf("topic patterns are only used with --prune")
+	}
+	spec, err := readTopicsSpec(a.fileName, a.format)
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var pruneCandidates []string
+	if a.shouldPrune {
+		pruneCandidates, _, err = a.selector.Select(client, args)
+		if err != nil {
+			return err
+		}
+	}
+	plans, err := buildTopicsPlan(client, spec, pruneCandidates)
+	if err != nil {
+		return err
+	}
+	printTopicsPlan(plans)
+	if a.isPlanOnly {
+		for _, plan := range plans {
+			if plan.hasDrift() {
+				client.Close()
+				os.Exit(2)
+			}
+		}
+		return nil
+	}
+	var names []string
+	var deletes []string
+	for _, plan := range plans {
+		if plan.hasChanges() {
+			names = append(names, plan.name)
+		}
+		if plan.shouldDelete {
+			deletes = append(deletes, plan.name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	err = a.guard.check(client, "deleted", deletes, true)
+	if err != nil {
+		return err
+	}
+	applyAudit := newAudit(client)
+	err = applyAudit.track(auditTopic, names...)
+	if err != nil {
+		return err
+	}
+	applyAudit.deleting(deletes...)
+	return applyAudit.finish(applyTopicsPlan(client, plans))
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [topics or glob patterns to prune]",
+	Short: "bring topics in line with a YAML/JSON spec"}
+
+var planCmd = &cobra.Command{
+	Use:   "plan [topics or glob patterns to prune]",
+	Short: "show the difference between a YAML/JSON spec and the cluster (exit code 2 on drift)"}
+
+func init() {
+	var applyRunner applyCmdType
+	var planRunner = applyCmdType{isPlanOnly: true}
+	applyCmd.RunE = applyRunner.Run
+	planCmd.RunE = planRunner.Run
+	for _, item := range []struct {
+		cmd    *cobra.Command
+		runner *applyCmdType
+	}{{applyCmd, &applyRunner}, {planCmd, &planRunner}} {
+		flags := item.cmd.Flags()
+		flags.StringVarP(&item.runner.fileName, "file", "f", "", "topics spec file")
+		flags.StringVarP(&item.runner.format, "format", "F", "", "spec format: yaml or json (detected by file extension if not set)")
+		flags.BoolVar(&item.runner.shouldPrune, "prune", false, "delete topics missing from the spec that match the topic patterns or selector flags")
+		item.runner.selector.addFlags(flags)
+	}
+	applyRunner.guard.addFlags(applyCmd.Flags())
+}
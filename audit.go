@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	auditTopic        = "topic"
+	auditBroker       = "broker"
+	auditBrokerLogger = "broker-logger"
+	auditAcl          = "acl"
+	auditQuota        = "quota"
+	auditUser         = "user"
+	auditReassignment = "reassignment"
+	auditElection     = "election"
+	auditLogDir       = "log-dir"
+)
+
+var auditResourceTypes = map[string]sarama.ConfigResourceType{
+	auditTopic:        sarama.TopicResource,
+	auditBroker:       sarama.BrokerResource,
+	auditBrokerLogger: sarama.BrokerLoggerResource}
+
+type auditState struct {
+	Partitions        int               `json:"partitions,omitempty"`
+	ReplicationFactor int               `json:"replicationFactor,omitempty"`
+	Configs           map[string]string `json:"configs"`
+}
+
+type auditChange struct {
+	Resource    string      `json:"resource"`
+	Name        string      `json:"name"`
+	Before      *auditState `json:"before,omitempty"`
+	After       *auditState `json:"after,omitempty"`
+	IsUnknown   bool        `json:"unknown,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+type auditRecord struct {
+	ID      string         `json:"id"`
+	Time    time.Time      `json:"time"`
+	User    string         `json:"user"`
+	Host    string         `json:"host"`
+	Cluster string         `json:"cluster"`
+	Command string         `json:"command"`
+	Changes []*auditChange `json:"changes"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func auditFileName() (string, error) {
+	config, err := loadToolConfig()
+	if err != nil {
+		return "", err
+	}
+	if len(config.AuditFile) > 0 {
+		return config.AuditFile, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kafkatool", "audit.jsonl"), nil
+}
+
+func readAuditRecords() ([]*auditRecord, error) {
+	fileName, err := auditFileName()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var result []*auditRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record auditRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, line, err)
+		}
+		result = append(result, &record)
+	}
+	return result, scanner.Err()
+}
+
+func appendAuditRecord(record *auditRecord) error {
+	fileName, err := auditFileName()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(fileName), 0700)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func snapshotResources(client sarama.Client, resource string, names []string) (map[string]*auditState, error) {
+	result := make(map[string]*auditState)
+	existing := names
+	if resource == auditTopic {
+		existing = nil
+		metadata, err := kafkaadmin.GetMetadata(client, names...)
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range metadata.Topics {
+			if topic.Err != sarama.ErrNoError {
+				continue
+			}
+			state := &auditState{Partitions: len(topic.Partitions)}
+			if len(topic.Partitions) > 0 {
+				state.ReplicationFactor = len(topic.Partitions[0].Replicas)
+			}
+			result[topic.Name] = state
+			existing = append(existing, topic.Name)
+		}
+	}
+	if len(existing) == 0 {
+		return result, nil
+	}
+	configs, err := kafkaadmin.DynamicConfigs(client, auditResourceTypes[resource], existing...)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range existing {
+		if result[name] == nil {
+			result[name] = &auditState{}
+		}
+		result[name].Configs = configs[name]
+	}
+	return result, nil
+}
+
+type auditTarget struct {
+	resource string
+	names    []string
+	before   map[string]*auditState
+	deleted  map[string]bool
+}
+
+type audit struct {
+	client    sarama.Client
+	targets   []*auditTarget
+	described []*auditChange
+}
+
+func newAudit(client sarama.Client) *audit {
+	return &audit{client: client}
+}
+
+func (a *audit) track(resource string, names ...string) error {
+	before, err := snapshotResources(a.client, resource, names)
+	if err != nil {
+		return fmt.Errorf("audit: cannot read the state before changes: %w", err)
+	}
+	a.targets = append(a.targets, &auditTarget{resource: resource, names: names, before: before, deleted: make(map[string]bool)})
+	return nil
+}
+
+// deleting marks tracked topics the command deletes. Deletion completes asynchronously, so a successful command
+// records them as deleted whatever the metadata still shows.
+func (a *audit) deleting(names ...string) {
+	for _, target := range a.targets {
+		if target.resource != auditTopic {
+			continue
+		}
+		for _, name := range names {
+			target.deleted[name] = true
+		}
+	}
+}
+
+// describe records a change the audit cannot take a snapshot of (acls, quotas, credentials, replica moves,
+// elections), it is written even if the command fails as the change may be partially done.
+func (a *audit) describe(resource string, name string, format string, args ...interface{}) {
+	a.described = append(a.described, &auditChange{Resource: resource, Name: name, Description: fmt.Sprintf(format, args...)})
+}
+
+// auditID is unique across processes writing the same file within a millisecond.
+func auditID(now time.Time) string {
+	random := make([]byte, 4)
+	_, err := rand.Read(random)
+	if err != nil {
+		return strconv.FormatInt(now.UnixNano(), 36)
+	}
+	return strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 36) + "-" + hex.EncodeToString(random)
+}
+
+func currentUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+func (a *audit) finish(commandErr error) error {
+	now := time.Now()
+	record := &auditRecord{
+		ID:      auditID(now),
+		Time:    now,
+		User:    currentUser(),
+		Cluster: hostPort.String(),
+		Command: strings.Join(os.Args[1:], " "),
+		Changes: []*auditChange{}}
+	record.Host, _ = os.Hostname()
+	if commandErr != nil {
+		record.Error = commandErr.Error()
+	}
+	for _, target := range a.targets {
+		after, err := snapshotResources(a.client, target.resource, target.names)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "audit: cannot read the state after changes:", err)
+		}
+		for _, name := range target.names {
+			change := &auditChange{Resource: target.resource, Name: name, Before: target.before[name], After: after[name]}
+			if commandErr == nil && target.deleted[name] {
+				change.After = nil
+			} else if err != nil {
+				change.IsUnknown = true
+			}
+			if !change.isEmpty() {
+				record.Changes = append(record.Changes, change)
+			}
+		}
+	}
+	record.Changes = append(record.Changes, a.described...)
+	err := appendAuditRecord(record)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit: cannot write the record:", err)
+	} else {
+		fmt.Fprintln(os.Stderr, "audit record", record.ID)
+	}
+	return commandErr
+}
+
+func (c *auditChange) isEmpty() bool {
+	if c.IsUnknown || len(c.Description) > 0 {
+		return false
+	}
+	if c.Before == nil || c.After == nil {
+		return c.Before == c.After
+	}
+	return c.Before.Partitions == c.After.Partitions && len(c.changedConfigs()) == 0
+}
+
+func (c *auditChange) changedConfigs() []string {
+	var result []string
+	for key, value := range c.Before.Configs {
+		if after, ok := c.After.Configs[key]; !ok || after != value {
+			result = append(result, key)
+		}
+	}
+	for key := range c.After.Configs {
+		if _, ok := c.Before.Configs[key]; !ok {
+			result = append(result, key)
+		}
+	}
+	return result
+}
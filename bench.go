@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latency histogram buckets are log-linear in microseconds, 128 sub-buckets per power of two keep the error
+// below 1%.
+const (
+	histogramLinear     = 256
+	histogramSubBuckets = 128
+)
+
+type latencyHistogram struct {
+	counts []int64
+	count  int64
+	max    time.Duration
+}
+
+func histogramIndex(micros uint64) int {
+	if micros < histogramLinear {
+		return int(micros)
+	}
+	shift := bits.Len64(micros) - 8
+	return histogramLinear + (shift-1)*histogramSubBuckets + int(micros>>uint(shift)) - histogramSubBuckets
+}
+
+func histogramValue(index int) time.Duration {
+	if index < histogramLinear {
+		return time.Duration(index) * time.Microsecond
+	}
+	offset := index - histogramLinear
+	shift := uint(offset/histogramSubBuckets + 1)
+	low := uint64(offset%histogramSubBuckets+histogramSubBuckets) << shift
+	return time.Duration(low+(uint64(1)<<shift)/2) * time.Microsecond
+}
+
+func (h *latencyHistogram) record(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	index := histogramIndex(uint64(latency / time.Microsecond))
+	for len(h.counts) <= index {
+		h.counts = append(h.counts, 0)
+	}
+	h.counts[index]++
+	h.count++
+	if latency > h.max {
+		h.max = latency
+	}
+}
+
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	for len(h.counts) < len(other.counts) {
+		h.counts = append(h.counts, 0)
+	}
+	for index, count := range other.counts {
+		h.counts[index] += count
+	}
+	h.count += other.count
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := int64(q * float64(h.count))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for index, count := range h.counts {
+		seen += count
+		if seen >= target {
+			value := histogramValue(index)
+			if value > h.max {
+				return h.max
+			}
+			return value
+		}
+	}
+	return h.max
+}
+
+type latencySummary struct {
+	P50 float64 `json:"p50Ms"`
+	P95 float64 `json:"p95Ms"`
+	P99 float64 `json:"p99Ms"`
+	Max float64 `json:"maxMs"`
+}
+
+func milliseconds(value time.Duration) float64 {
+	return float64(value) / float64(time.Millisecond)
+}
+
+func (h *latencyHistogram) summary() *latencySummary {
+	return &latencySummary{
+		P50: milliseconds(h.quantile(0.5)),
+		P95: milliseconds(h.quantile(0.95)),
+		P99: milliseconds(h.quantile(0.99)),
+		Max: milliseconds(h.max)}
+}
+
+type benchLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newBenchLimiter(rate float64) *benchLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &benchLimiter{interval: time.Duration(float64(time.Second) / rate), next: time.Now()}
+}
+
+func (l *benchLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now.Add(-time.Second)) {
+		l.next = now //do not burst after a stall
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+	if delay := time.Until(slot); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+type benchMeter struct {
+	messages  int64
+	bytes     int64
+	errors    int64
+	mutex     sync.Mutex
+	latencies latencyHistogram
+}
+
+func (m *benchMeter) add(messages int64, bytes int64) {
+	atomic.AddInt64(&m.messages, messages)
+	atomic.AddInt64(&m.bytes, bytes)
+}
+
+func (m *benchMeter) addError() {
+	atomic.AddInt64(&m.errors, 1)
+}
+
+func (m *benchMeter) recordLatency(latency time.Duration) {
+	m.mutex.Lock()
+	m.latencies.record(latency)
+	m.mutex.Unlock()
+}
+
+type benchInterval struct {
+	Elapsed        float64         `json:"elapsedSeconds"`
+	Messages       int64           `json:"messages"`
+	Bytes          int64           `json:"bytes"`
+	Errors         int64           `json:"errors,omitempty"`
+	MessagesPerSec float64         `json:"messagesPerSec"`
+	MBPerSec       float64         `json:"mbPerSec"`
+	Latency        *latencySummary `json:"latency,omitempty"`
+	Fetches        int64           `json:"fetches,omitempty"`
+	BytesPerFetch  float64         `json:"bytesPerFetch,omitempty"`
+}
+
+func newBenchInterval(elapsed time.Duration, duration time.Duration, messages int64, bytes int64, errors int64) *benchInterval {
+	result := &benchInterval{Elapsed: elapsed.Seconds(), Messages: messages, Bytes: bytes, Errors: errors}
+	if duration > 0 {
+		result.MessagesPerSec = float64(messages) / duration.Seconds()
+		result.MBPerSec = float64(bytes) / duration.Seconds() / (1024 * 1024)
+	}
+	return result
+}
+
+func (b *benchInterval) String() string {
+	result := fmt.Sprintf("%7.1fs %10.0f msg/s %8.2f MB/s", b.Elapsed, b.MessagesPerSec, b.MBPerSec)
+	if b.Latency != nil {
+		result += fmt.Sprintf("  latency p50 %.1fms p95 %.1fms p99 %.1fms max %.1fms", b.Latency.P50, b.Latency.P95, b.Latency.P99, b.Latency.Max)
+	}
+	if b.Fetches > 0 {
+		result += fmt.Sprintf("  %d fetches %s/fetch", b.Fetches, formatBinarySize(int64(b.BytesPerFetch)))
+	}
+	if b.Errors > 0 {
+		result += fmt.Sprintf("  %d errors", b.Errors)
+	}
+	return result
+}
+
+func (m *benchMeter) collect(total *latencyHistogram, withLatency bool, elapsed time.Duration, duration time.Duration) *benchInterval {
+	result := newBenchInterval(elapsed, duration, atomic.SwapInt64(&m.messages, 0), atomic.SwapInt64(&m.bytes, 0), atomic.SwapInt64(&m.errors, 0))
+	if withLatency {
+		m.mutex.Lock()
+		latencies := m.latencies
+		m.latencies = latencyHistogram{}
+		m.mutex.Unlock()
+		result.Latency = latencies.summary()
+		total.merge(&latencies)
+	}
+	return result
+}
+
+// metricCount returns the cumulative count of a sarama meter, 0 if it is not registered yet.
+func metricCount(registry interface{ Get(string) interface{} }, name string) int64 {
+	if meter, ok := registry.Get(name).(interface{ Count() int64 }); ok {
+		return meter.Count()
+	}
+	return 0
+}
+
+func interruptChannel() <-chan struct{} {
+	result := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		close(result)
+	}()
+	return result
+}
+
+// benchReport is where the progress goes, stderr when the JSON results are written to stdout.
+func benchReport(resultsFile string) io.Writer {
+	if resultsFile == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func writeBenchResults(fileName string, results interface{}) error {
+	if len(fileName) == 0 {
+		return nil
+	}
+	if fileName == "-" {
+		return writeStructured(os.Stdout, "json", results)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeStructured(file, "json", results)
+}
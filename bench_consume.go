@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainTracker tracks the partitions still behind the high watermarks taken at the start.
+type drainTracker struct {
+	mutex       sync.Mutex
+	targets     map[int32]int64
+	drained     chan struct{}
+	drainedAt   time.Time
+	lastMessage time.Time
+}
+
+func newDrainTracker(targets map[int32]int64) *drainTracker {
+	result := &drainTracker{targets: targets, drained: make(chan struct{})}
+	if len(targets) == 0 {
+		result.drainedAt = time.Now()
+		close(result.drained)
+	}
+	return result
+}
+
+func (d *drainTracker) consumed(partition int32, offset int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.lastMessage = time.Now()
+	target, ok := d.targets[partition]
+	if !ok || offset+1 < target {
+		return
+	}
+	d.drain(partition, d.lastMessage)
+}
+
+// idle is called when a partition got no messages for longer than a fetch waits. The records between its last
+// message and a high watermark at or past the target are control records of transactions, which are never delivered.
+func (d *drainTracker) idle(partition int32, highWaterMark int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	target, ok := d.targets[partition]
+	if !ok || highWaterMark < target {
+		return
+	}
+	at := d.lastMessage
+	if at.IsZero() {
+		at = time.Now()
+	}
+	d.drain(partition, at)
+}
+
+func (d *drainTracker) drain(partition int32, at time.Time) {
+	delete(d.targets, partition)
+	if len(d.targets) == 0 {
+		d.drainedAt = at
+		close(d.drained)
+	}
+}
+
+type benchConsumeResults struct {
+	Topic        string           `json:"topic"`
+	Group        string           `json:"group,omitempty"`
+	FetchMin     int32            `json:"fetchMin"`
+	FetchDefault int32            `json:"fetchDefault"`
+	FetchMax     int32            `json:"fetchMax,omitempty"`
+	MaxWaitMs    int64            `json:"maxWaitMs"`
+	DrainSeconds float64          `json:"drainSeconds,omitempty"`
+	Intervals    []*benchInterval `json:"intervals"`
+	Total        *benchInterval   `json:"total"`
+}
+
+type benchConsumeCmdType struct {
+	group          string
+	shouldStartEnd bool
+	shouldFollow   bool
+	count          int64
+	duration       time.Duration
+	fetchMin       string
+	fetchDefault   string
+	fetchMax       string
+	maxWait        time.Duration
+	interval       time.Duration
+	resultsFile    string
+	meter          benchMeter
+	consumed       int64
+	tracker        *drainTracker
+	countReached   chan struct{}
+	countOnce      sync.Once
+}
+
+func (b *benchConsumeCmdType) config() (*sarama.Config, error) {
+	config := kafkaadmin.NewConfig()
+	for _, item := range []struct {
+		value  string
+		target *int32
+	}{{b.fetchMin, &config.Consumer.Fetch.Min}, {b.fetchDefault, &config.Consumer.Fetch.Default}, {b.fetchMax, &config.Consumer.Fetch.Max}} {
+		if len(item.value) == 0 {
+			continue
+		}
+		size, err := parseBinarySize(item.value)
+		if err != nil || size < 0 || size > 1<<31-1 {
+			return nil, fmt.Errorf("invalid fetch size %s", item.value)
+		}
+		*item.target = int32(size)
+	}
+	if b.maxWait > 0 {
+		config.Consumer.MaxWaitTime = b.maxWait
+	}
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if b.shouldStartEnd {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+	return config, config.Validate()
+}
+
+// idlePeriod is long enough for a fetch to return whatever is available below the high watermark.
+func idlePeriod(config *sarama.Config) time.Duration {
+	return 2*config.Consumer.MaxWaitTime + time.Second
+}
+
+func (b *benchConsumeCmdType) record(message *sarama.ConsumerMessage) {
+	b.meter.add(1, int64(len(message.Key)+len(message.Value)))
+	b.tracker.consumed(message.Partition, message.Offset)
+	if b.count > 0 && atomic.AddInt64(&b.consumed, 1) >= b.count {
+		b.countOnce.Do(func() {
+			close(b.countReached)
+		})
+	}
+}
+
+func (b *benchConsumeCmdType) drainTargets(client sarama.Client, topic string, partitions []int32) (map[int32]int64, error) {
+	ranges, err := kafkaadmin.GetTopicRanges(client, kafkaadmin.TopicPartitions{topic: partitions}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var committed kafkaadmin.GroupOffsets
+	if len(b.group) > 0 {
+		committed, err = kafkaadmin.GetGroupOffsets(client, b.group)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[int32]int64)
+	for partition, offsetRange := range ranges[topic] {
+		start := offsetRange.Oldest
+		if b.shouldStartEnd {
+			start = offsetRange.Newest
+		}
+		if offset, ok := committed[topic][partition]; ok && offset.Offset > start {
+			start = offset.Offset
+		}
+		if start < offsetRange.Newest {
+			result[partition] = offsetRange.Newest
+		}
+	}
+	return result, nil
+}
+
+func (b *benchConsumeCmdType) consumePartitions(config *sarama.Config, topic string, partitions []int32, stop <-chan struct{}) error {
+	consumer, err := sarama.NewConsumer([]string{hostPort.String()}, config)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+	var consumers []sarama.PartitionConsumer
+	defer func() {
+		for _, partitionConsumer := range consumers {
+			partitionConsumer.AsyncClose()
+		}
+	}()
+	for _, partition := range partitions {
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, config.Consumer.Offsets.Initial)
+		if err != nil {
+			return err
+		}
+		consumers = append(consumers, partitionConsumer)
+	}
+	var wait sync.WaitGroup
+	for i, partitionConsumer := range consumers {
+		wait.Add(1)
+		go func(partition int32, partitionConsumer sarama.PartitionConsumer) {
+			defer wait.Done()
+			idle := time.NewTicker(idlePeriod(config))
+			defer idle.Stop()
+			hasMessages := false
+			for {
+				select {
+				case message := <-partitionConsumer.Messages():
+					b.record(message)
+					hasMessages = true
+				case <-idle.C:
+					if !hasMessages {
+						b.tracker.idle(partition, partitionConsumer.HighWaterMarkOffset())
+					}
+					hasMessages = false
+				case <-stop:
+					return
+				}
+			}
+		}(partitions[i], partitionConsumer)
+	}
+	wait.Wait()
+	return nil
+}
+
+type benchGroupHandler struct {
+	bench  *benchConsumeCmdType
+	config *sarama.Config
+}
+
+func (h *benchGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *benchGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *benchGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	idle := time.NewTicker(idlePeriod(h.config))
+	defer idle.Stop()
+	hasMessages := false
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			h.bench.record(message)
+			session.MarkMessage(message, "")
+			hasMessages = true
+		case <-idle.C:
+			if !hasMessages {
+				h.bench.tracker.idle(claim.Partition(), claim.HighWaterMarkOffset())
+			}
+			hasMessages = false
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (b *benchConsumeCmdType) consumeGroup(config *sarama.Config, topic string, stop <-chan struct{}) error {
+	group, err := sarama.NewConsumerGroup([]string{hostPort.String()}, b.group, config)
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+	for ctx.Err() == nil {
+		err = group.Consume(ctx, []string{topic}, &benchGroupHandler{bench: b, config: config})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *benchConsumeCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("topic expected")
+	}
+	topic := args[0]
+	if b.interval <= 0 {
+		return fmt.Errorf("interval has to be positive")
+	}
+	config, err := b.config()
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("topic %s: %w", topic, err)
+	}
+	targets, err := b.drainTargets(client, topic, partitions)
+	if err != nil {
+		return err
+	}
+	isFollowing := b.shouldFollow || b.shouldStartEnd
+	if !isFollowing && len(targets) == 0 {
+		fmt.Fprintln(benchReport(b.resultsFile), "nothing to consume, use --follow to wait for new messages")
+		return nil
+	}
+	b.tracker = newDrainTracker(targets)
+	b.countReached = make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		if len(b.group) > 0 {
+			done <- b.consumeGroup(config, topic, stop)
+		} else {
+			done <- b.consumePartitions(config, topic, partitions, stop)
+		}
+	}()
+	var timeout <-chan time.Time
+	if b.duration > 0 {
+		timeout = time.After(b.duration)
+	}
+	var drained <-chan struct{}
+	if !isFollowing {
+		drained = b.tracker.drained
+	}
+	interrupt := interruptChannel()
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	report := benchReport(b.resultsFile)
+	results := &benchConsumeResults{Topic: topic, Group: b.group, FetchMin: config.Consumer.Fetch.Min,
+		FetchDefault: config.Consumer.Fetch.Default, FetchMax: config.Consumer.Fetch.Max,
+		MaxWaitMs: config.Consumer.MaxWaitTime.Milliseconds()}
+	var messages, bytes, lastFetches, lastFetchBytes int64
+	last := start
+	collect := func() {
+		now := time.Now()
+		interval := b.meter.collect(nil, false, now.Sub(start), now.Sub(last))
+		last = now
+		fetches := metricCount(config.MetricRegistry, "consumer-fetch-rate")
+		fetchBytes := metricCount(config.MetricRegistry, "incoming-byte-rate")
+		interval.Fetches = fetches - lastFetches
+		if interval.Fetches > 0 {
+			interval.BytesPerFetch = float64(fetchBytes-lastFetchBytes) / float64(interval.Fetches)
+		}
+		lastFetches, lastFetchBytes = fetches, fetchBytes
+		messages += interval.Messages
+		bytes += interval.Bytes
+		results.Intervals = append(results.Intervals, interval)
+		fmt.Fprintln(report, interval)
+	}
+	isFinished := false
+	for isRunning := true; isRunning; {
+		select {
+		case <-ticker.C:
+			collect()
+		case <-timeout:
+			isRunning = false
+		case <-interrupt:
+			isRunning = false
+		case <-b.countReached:
+			isRunning = false
+		case <-drained:
+			isRunning = false
+		case err = <-done:
+			if err != nil {
+				return err
+			}
+			isFinished = true
+			isRunning = false
+		}
+	}
+	collect()
+	elapsed := time.Since(start)
+	close(stop)
+	if !isFinished {
+		err = <-done
+		if err != nil {
+			return err
+		}
+	}
+	results.Total = newBenchInterval(elapsed, elapsed, messages, bytes, 0)
+	results.Total.Fetches = lastFetches
+	if lastFetches > 0 {
+		results.Total.BytesPerFetch = float64(lastFetchBytes) / float64(lastFetches)
+	}
+	fmt.Fprintln(report, "total:")
+	fmt.Fprintln(report, results.Total)
+	select {
+	case <-b.tracker.drained:
+		results.DrainSeconds = b.tracker.drainedAt.Sub(start).Seconds()
+		fmt.Fprintf(report, "drained in %.1fs\n", results.DrainSeconds)
+	default:
+		fmt.Fprintln(report, "not drained")
+	}
+	return writeBenchResults(b.resultsFile, results)
+}
+
+var benchConsumeCmd = &cobra.Command{
+	Use:   "consume <topic>",
+	Short: "consume all partitions (or with --group) until the high watermarks at the start are reached, reporting throughput and fetches"}
+
+func init() {
+	var runner benchConsumeCmdType
+	benchConsumeCmd.RunE = runner.Run
+	flags := benchConsumeCmd.Flags()
+	flags.StringVarP(&runner.group, "group", "g", "", "consume with this consumer group (its offsets are committed)")
+	flags.BoolVar(&runner.shouldStartEnd, "from-end", false, "start from the newest offsets (implies --follow)")
+	flags.BoolVarP(&runner.shouldFollow, "follow", "f", false, "keep consuming after the topic is drained")
+	flags.Int64VarP(&runner.count, "count", "n", 0, "stop after the number of messages")
+	flags.DurationVarP(&runner.duration, "duration", "d", 0, "stop after the time")
+	flags.StringVar(&runner.fetchMin, "fetch-min", "", "minimum bytes of a fetch response (Consumer.Fetch.Min)")
+	flags.StringVar(&runner.fetchDefault, "fetch-default", "", "bytes fetched per partition (Consumer.Fetch.Default)")
+	flags.StringVar(&runner.fetchMax, "fetch-max", "", "maximum bytes fetched per partition, 0 for unlimited (Consumer.Fetch.Max)")
+	flags.DurationVar(&runner.maxWait, "max-wait", 0, "maximum time the broker waits for --fetch-min bytes (Consumer.MaxWaitTime)")
+	flags.DurationVarP(&runner.interval, "interval", "i", 5*time.Second, "reporting interval")
+	flags.StringVar(&runner.resultsFile, "results", "", "write JSON results to the file (- for stdout)")
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type messageSizes struct {
+	values      []int
+	weights     []int
+	totalWeight int
+	isRange     bool
+}
+
+func parseMessageSizes(value string) (*messageSizes, error) {
+	result := &messageSizes{}
+	parseSize := func(item string) (int, error) {
+		size, err := parseBinarySize(item)
+		if err != nil || size < 0 || size > 1024*1024*1024 {
+			return 0, fmt.Errorf("invalid message size %s", item)
+		}
+		return int(size), nil
+	}
+	if index := strings.Index(value, "-"); index > 0 && !strings.Contains(value, ",") {
+		low, err := parseSize(value[:index])
+		if err != nil {
+			return nil, err
+		}
+		high, err := parseSize(value[index+1:])
+		if err != nil {
+			return nil, err
+		}
+		if high < low {
+			return nil, fmt.Errorf("invalid message size range %s", value)
+		}
+		result.values = []int{low, high}
+		result.isRange = true
+		return result, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		weight := 1
+		if index := strings.Index(item, ":"); index >= 0 {
+			var err error
+			weight, err = strconv.Atoi(item[index+1:])
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight in %s", item)
+			}
+			item = item[:index]
+		}
+		size, err := parseSize(item)
+		if err != nil {
+			return nil, err
+		}
+		result.values = append(result.values, size)
+		result.weights = append(result.weights, weight)
+		result.totalWeight += weight
+	}
+	return result, nil
+}
+
+func (m *messageSizes) max() int {
+	result := 0
+	for _, value := range m.values {
+		if value > result {
+			result = value
+		}
+	}
+	return result
+}
+
+func (m *messageSizes) next(random *rand.Rand) int {
+	if m.isRange {
+		return m.values[0] + random.Intn(m.values[1]-m.values[0]+1)
+	}
+	if len(m.values) == 1 {
+		return m.values[0]
+	}
+	point := random.Intn(m.totalWeight)
+	for i, weight := range m.weights {
+		if point < weight {
+			return m.values[i]
+		}
+		point -= weight
+	}
+	return m.values[len(m.values)-1]
+}
+
+func parseAcks(value string) (sarama.RequiredAcks, error) {
+	switch strings.ToLower(value) {
+	case "0":
+		return sarama.NoResponse, nil
+	case "1":
+		return sarama.WaitForLocal, nil
+	case "all", "-1":
+		return sarama.WaitForAll, nil
+	}
+	return 0, fmt.Errorf("invalid acks %s, expected 0, 1 or all", value)
+}
+
+type benchProduceResults struct {
+	Topic       string           `json:"topic"`
+	Size        string           `json:"size"`
+	Keys        int              `json:"keys"`
+	Rate        float64          `json:"rate,omitempty"`
+	Acks        string           `json:"acks"`
+	Compression string           `json:"compression"`
+	Concurrency int              `json:"concurrency"`
+	Intervals   []*benchInterval `json:"intervals"`
+	Total       *benchInterval   `json:"total"`
+}
+
+type benchProduceCmdType struct {
+	size          string
+	keys          int
+	rate          float64
+	duration      time.Duration
+	count         int64
+	batchBytes    int
+	batchMessages int
+	linger        time.Duration
+	compression   string
+	acks          string
+	concurrency   int
+	interval      time.Duration
+	resultsFile   string
+}
+
+func (b *benchProduceCmdType) config(maxSize int) (*sarama.Config, error) {
+	config := kafkaadmin.NewConfig()
+	acks, err := parseAcks(b.acks)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.RequiredAcks = acks
+	err = config.Producer.Compression.UnmarshalText([]byte(strings.ToLower(b.compression)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compression %s, expected none, gzip, snappy, lz4 or zstd", b.compression)
+	}
+	config.Producer.Flush.Bytes = b.batchBytes
+	config.Producer.Flush.Messages = b.batchMessages
+	config.Producer.Flush.Frequency = b.linger
+	config.Producer.Return.Successes = true
+	if maxSize+1024 > config.Producer.MaxMessageBytes {
+		config.Producer.MaxMessageBytes = maxSize + 1024
+	}
+	return config, config.Validate()
+}
+
+func (b *benchProduceCmdType) produce(producer sarama.AsyncProducer, topic string, sizes *messageSizes,
+	limiter *benchLimiter, claimed *int64, stop <-chan struct{}, seed int64) {
+	defer producer.AsyncClose()
+	random := rand.New(rand.NewSource(seed))
+	payload := make([]byte, 2*sizes.max()+1)
+	random.Read(payload)
+	for {
+		if b.count > 0 && atomic.AddInt64(claimed, 1) > b.count {
+			return
+		}
+		limiter.wait()
+		size := sizes.next(random)
+		offset := random.Intn(len(payload) - size) //messages of a batch differ and do not compress unrealistically
+		message := &sarama.ProducerMessage{
+			Topic:    topic,
+			Value:    sarama.ByteEncoder(payload[offset : offset+size]),
+			Metadata: time.Now()}
+		if b.keys > 0 {
+			message.Key = sarama.StringEncoder("key-" + strconv.Itoa(random.Intn(b.keys)))
+		}
+		select {
+		case producer.Input() <- message:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func messageBytes(message *sarama.ProducerMessage) int64 {
+	result := int64(message.Value.Length())
+	if message.Key != nil {
+		result += int64(message.Key.Length())
+	}
+	return result
+}
+
+func (b *benchProduceCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("topic expected")
+	}
+	topic := args[0]
+	if b.concurrency < 1 || b.interval <= 0 {
+		return fmt.Errorf("concurrency and interval have to be positive")
+	}
+	sizes, err := parseMessageSizes(b.size)
+	if err != nil {
+		return err
+	}
+	config, err := b.config(sizes.max())
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("topic %s: %w", topic, err)
+	}
+	var producers []sarama.AsyncProducer
+	for i := 0; i < b.concurrency; i++ {
+		producer, err := sarama.NewAsyncProducer([]string{hostPort.String()}, config)
+		if err != nil {
+			for _, producer := range producers {
+				producer.Close()
+			}
+			return err
+		}
+		producers = append(producers, producer)
+	}
+	var meter benchMeter
+	var workers sync.WaitGroup
+	var claimed int64
+	stop := make(chan struct{})
+	limiter := newBenchLimiter(b.rate)
+	start := time.Now()
+	for i, producer := range producers {
+		workers.Add(3)
+		go func(producer sarama.AsyncProducer, seed int64) {
+			defer workers.Done()
+			b.produce(producer, topic, sizes, limiter, &claimed, stop, seed)
+		}(producer, start.UnixNano()+int64(i))
+		go func(producer sarama.AsyncProducer) {
+			defer workers.Done()
+			for message := range producer.Successes() {
+				meter.add(1, messageBytes(message))
+				meter.recordLatency(time.Since(message.Metadata.(time.Time)))
+			}
+		}(producer)
+		go func(producer sarama.AsyncProducer) {
+			defer workers.Done()
+			for range producer.Errors() {
+				meter.addError()
+			}
+		}(producer)
+	}
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	var timeout <-chan time.Time
+	if b.duration > 0 {
+		timeout = time.After(b.duration)
+	}
+	interrupt := interruptChannel()
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	report := benchReport(b.resultsFile)
+	results := &benchProduceResults{Topic: topic, Size: b.size, Keys: b.keys, Rate: b.rate, Acks: b.acks,
+		Compression: config.Producer.Compression.String(), Concurrency: b.concurrency}
+	var total latencyHistogram
+	var messages, bytes, errors int64
+	last := start
+	collect := func() {
+		now := time.Now()
+		interval := meter.collect(&total, true, now.Sub(start), now.Sub(last))
+		last = now
+		messages += interval.Messages
+		bytes += interval.Bytes
+		errors += interval.Errors
+		results.Intervals = append(results.Intervals, interval)
+		fmt.Fprintln(report, interval)
+	}
+	stopOnce := sync.Once{}
+	stopAll := func() {
+		stopOnce.Do(func() {
+			close(stop)
+		})
+	}
+	for isRunning := true; isRunning; {
+		select {
+		case <-ticker.C:
+			collect()
+		case <-timeout:
+			stopAll()
+		case <-interrupt:
+			stopAll()
+		case <-done:
+			isRunning = false
+		}
+	}
+	collect()
+	elapsed := time.Since(start)
+	results.Total = newBenchInterval(elapsed, elapsed, messages, bytes, errors)
+	results.Total.Latency = total.summary()
+	fmt.Fprintln(report, "total:")
+	fmt.Fprintln(report, results.Total)
+	return writeBenchResults(b.resultsFile, results)
+}
+
+var benchProduceCmd = &cobra.Command{
+	Use:   "produce <topic>",
+	Short: "produce generated messages and report throughput and latency, runs until interrupted without --count or --duration"}
+
+func init() {
+	var runner benchProduceCmdType
+	benchProduceCmd.RunE = runner.Run
+	flags := benchProduceCmd.Flags()
+	flags.StringVarP(&runner.size, "size", "s", "1K", "message size: fixed (1K), uniform range (100-10K) or weighted list (100:9,10K:1)")
+	flags.IntVarP(&runner.keys, "keys", "k", 0, "number of distinct keys (no keys by default)")
+	flags.Float64VarP(&runner.rate, "rate", "r", 0, "messages per second limit (unlimited by default)")
+	flags.DurationVarP(&runner.duration, "duration", "d", 0, "run time")
+	flags.Int64VarP(&runner.count, "count", "n", 0, "number of messages")
+	flags.IntVar(&runner.batchBytes, "batch-bytes", 0, "bytes triggering a flush (producer default if not set)")
+	flags.IntVar(&runner.batchMessages, "batch-messages", 0, "messages triggering a flush (producer default if not set)")
+	flags.DurationVar(&runner.linger, "linger", 0, "maximum time before a flush (producer default if not set)")
+	flags.StringVarP(&runner.compression, "compression", "z", "none", "compression: none, gzip, snappy, lz4 or zstd")
+	flags.StringVar(&runner.acks, "acks", "1", "required acks: 0, 1 or all")
+	flags.IntVarP(&runner.concurrency, "concurrency", "c", 1, "number of producers")
+	flags.DurationVarP(&runner.interval, "interval", "i", 5*time.Second, "reporting interval")
+	flags.StringVar(&runner.resultsFile, "results", "", "write JSON results to the file (- for stdout)")
+}
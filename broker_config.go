@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"sort"
+)
+
+type brokerConfigCmdType struct {
+	isDefault          bool
+	isLogger           bool
+	shouldPrintDefault bool
+}
+
+func (b *brokerConfigCmdType) resource(args []string) (sarama.ConfigResourceType, string, []string, error) {
+	resourceType := sarama.BrokerResource
+	if b.isLogger {
+		resourceType = sarama.BrokerLoggerResource
+	}
+	if b.isDefault {
+		if b.isLogger {
+			return resourceType, "", nil, fmt.Errorf("broker loggers have no cluster-wide default")
+		}
+		return resourceType, "", args, nil
+	}
+	if len(args) == 0 {
+		return resourceType, "", nil, fmt.Errorf("broker id or --default expected")
+	}
+	return resourceType, args[0], args[1:], nil
+}
+
+func (b *brokerConfigCmdType) runGet(cmd *cobra.Command, args []string) error {
+	resourceType, name, keys, err := b.resource(args)
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	configs, err := kafkaadmin.GetConfigs(client, resourceType, name)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for configName := range configs[name] {
+		names = append(names, configName)
+	}
+	sort.Strings(names)
+	for _, configName := range names {
+		config := configs[name][configName]
+		if len(keys) > 0 && !inArray(configName, keys) {
+			continue
+		}
+		if len(keys) == 0 && !b.shouldPrintDefault && config.Default {
+			continue
+		}
+		value := config.Value
+		if config.Sensitive {
+			value = "(sensitive)"
+		}
+		fmt.Printf("%s=%s (%s", config.Name, value, config.Source)
+		if config.ReadOnly {
+			fmt.Printf(",readonly")
+		}
+		fmt.Println(")")
+		for _, synonym := range config.Synonyms {
+			if synonym.Source == config.Source && synonym.ConfigName == config.Name {
+				continue
+			}
+			fmt.Printf("\t%s=%s (%s)\n", synonym.ConfigName, synonym.ConfigValue, synonym.Source)
+		}
+	}
+	return nil
+}
+
+func (b *brokerConfigCmdType) alter(args []string, makeChanges func([]string) (map[string]string, []string, error)) error {
+	resourceType, name, rest, err := b.resource(args)
+	if err != nil {
+		return err
+	}
+	set, unset, err := makeChanges(rest)
+	if err != nil {
+		return err
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	resource := auditBroker
+	if b.isLogger {
+		resource = auditBrokerLogger
+	}
+	configAudit := newAudit(client)
+	err = configAudit.track(resource, name)
+	if err != nil {
+		return err
+	}
+	return configAudit.finish(kafkaadmin.AlterConfigs(client, resourceType, kafkaadmin.SetConfigChanges(set, unset), false, name))
+}
+
+func (b *brokerConfigCmdType) runSet(cmd *cobra.Command, args []string) error {
+	return b.alter(args, func(pairs []string) (map[string]string, []string, error) {
+		if len(pairs) == 0 {
+			return nil, nil, fmt.Errorf("key=value pairs expected")
+		}
+		set := make(map[string]string)
+		for _, pair := range pairs {
+			key, value, err := parseKeyValue(pair)
+			if err != nil {
+				return nil, nil, err
+			}
+			set[key] = value
+		}
+		return set, nil, nil
+	})
+}
+
+func (b *brokerConfigCmdType) runUnset(cmd *cobra.Command, args []string) error {
+	return b.alter(args, func(keys []string) (map[string]string, []string, error) {
+		if len(keys) == 0 {
+			return nil, nil, fmt.Errorf("config keys expected")
+		}
+		return nil, keys, nil
+	})
+}
+
+var brokerConfigGetCmd = &cobra.Command{
+	Use:   "get [id] [keys]",
+	Short: "show broker configs with their sources and synonyms"}
+
+var brokerConfigSetCmd = &cobra.Command{
+	Use:   "set [id] key=value...",
+	Short: "set dynamic broker configs"}
+
+var brokerConfigUnsetCmd = &cobra.Command{
+	Use:   "unset [id] keys...",
+	Short: "remove dynamic broker configs"}
+
+func init() {
+	var runner brokerConfigCmdType
+	brokerConfigGetCmd.RunE = runner.runGet
+	brokerConfigSetCmd.RunE = runner.runSet
+	brokerConfigUnsetCmd.RunE = runner.runUnset
+	for _, cmd := range []*cobra.Command{brokerConfigGetCmd, brokerConfigSetCmd, brokerConfigUnsetCmd} {
+		flags := cmd.Flags()
+		flags.BoolVar(&runner.isDefault, "default", false, "use the cluster-wide default instead of a broker id")
+		flags.BoolVarP(&runner.isLogger, "logger", "l", false, "broker loggers instead of broker configs")
+	}
+	brokerConfigGetCmd.Flags().BoolVarP(&runner.shouldPrintDefault, "all", "A", false, "print unchanged (default) configs too")
+}
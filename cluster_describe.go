@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+)
+
+type clusterDescribeCmdType struct {
+	shouldPrintApiVersions bool
+}
+
+func (c *clusterDescribeCmdType) Run(cmd *cobra.Command, args []string) error {
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	info, err := kafkaadmin.DescribeCluster(client)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Cluster ID:", info.ClusterID)
+	fmt.Println("Controller:", info.ControllerID)
+	fmt.Println(len(info.Brokers), "brokers:")
+	for _, broker := range info.Brokers {
+		fmt.Printf("\t%d %s", broker.ID, broker.Addr)
+		if len(broker.Rack) > 0 {
+			fmt.Printf(" rack %s", broker.Rack)
+		}
+		if broker.Controller {
+			fmt.Printf(" (controller)")
+		}
+		fmt.Println("")
+		fmt.Printf("\t\tpartitions %d, leaders %d\n", broker.Partitions, broker.Leaders)
+		if !c.shouldPrintApiVersions {
+			continue
+		}
+		fmt.Println("\t\tAPI versions:")
+		for _, version := range broker.ApiVersions {
+			fmt.Printf("\t\t\t%s (%d) %d-%d\n", version.Name, version.Key, version.MinVersion, version.MaxVersion)
+		}
+	}
+	return nil
+}
+
+var clusterDescribeCmd = &cobra.Command{
+	Use:     "describe",
+	Aliases: []string{"d"},
+	Short:   "show cluster id, controller and brokers"}
+
+func init() {
+	var runner clusterDescribeCmdType
+	clusterDescribeCmd.RunE = runner.Run
+	flags := clusterDescribeCmd.Flags()
+	flags.BoolVar(&runner.shouldPrintApiVersions, "api-versions", true, "print API versions supported by each broker")
+}
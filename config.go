@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+type toolConfig struct {
+	Presets         map[string]map[string]string `yaml:"presets"`
+	ProtectedTopics []string                     `yaml:"protectedTopics"`
+	AuditFile       string                       `yaml:"auditFile"`
+}
+
+var configFileName string
+
+func defaultConfigFileName() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kafkatool", "config.yaml")
+}
+
+func readToolConfig(fileName string) (*toolConfig, error) {
+	var config toolConfig
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return &config, nil
+}
+
+func loadToolConfig() (*toolConfig, error) {
+	fileName := configFileName
+	if len(fileName) == 0 {
+		fileName = defaultConfigFileName()
+		if len(fileName) == 0 {
+			return &toolConfig{}, nil
+		}
+		config, err := readToolConfig(fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			return &toolConfig{}, nil
+		}
+		return config, err
+	}
+	return readToolConfig(fileName)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type groupOffsetRecord struct {
+	Group     string `json:"group"`
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Timestamp int64  `json:"timestamp"`
+	Metadata  string `json:"metadata,omitempty"`
+}
+
+var groupOffsetsCSVHeader = []string{"group", "topic", "partition", "offset", "timestamp", "metadata"}
+
+func detectFormat(format string, fileName string) string {
+	if len(format) > 0 {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return "csv"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".tf":
+		return "terraform"
+	}
+	return "json"
+}
+
+func groupOffsetsFormat(format string, fileName string) (string, error) {
+	result := detectFormat(format, fileName)
+	if result != "json" && result != "csv" {
+		return "", fmt.Errorf("unsupported format %s, json or csv expected", result)
+	}
+	return result, nil
+}
+
+func writeGroupOffsets(writer io.Writer, format string, records []groupOffsetRecord) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "csv":
+		csvWriter := csv.NewWriter(writer)
+		err := csvWriter.Write(groupOffsetsCSVHeader)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			err = csvWriter.Write([]string{
+				record.Group,
+				record.Topic,
+				strconv.FormatInt(int64(record.Partition), 10),
+				strconv.FormatInt(record.Offset, 10),
+				strconv.FormatInt(record.Timestamp, 10),
+				record.Metadata})
+			if err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return fmt.Errorf("unsupported format %s", format)
+}
+
+func readGroupOffsets(reader io.Reader, format string) ([]groupOffsetRecord, error) {
+	var records []groupOffsetRecord
+	switch format {
+	case "json":
+		err := json.NewDecoder(reader).Decode(&records)
+		return records, err
+	case "csv":
+		rows, err := csv.NewReader(reader).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if i == 0 && len(row) > 0 && row[0] == groupOffsetsCSVHeader[0] {
+				continue
+			}
+			//files exported before the metadata column have one field less
+			if len(row) != len(groupOffsetsCSVHeader) && len(row) != len(groupOffsetsCSVHeader)-1 {
+				return nil, fmt.Errorf("line %d: %d fields expected, got %d", i+1, len(groupOffsetsCSVHeader), len(row))
+			}
+			record := groupOffsetRecord{Group: row[0], Topic: row[1]}
+			partition, err := strconv.ParseInt(row[2], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid partition: %w", i+1, err)
+			}
+			record.Partition = int32(partition)
+			record.Offset, err = strconv.ParseInt(row[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid offset: %w", i+1, err)
+			}
+			record.Timestamp, err = strconv.ParseInt(row[4], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid timestamp: %w", i+1, err)
+			}
+			if len(row) == len(groupOffsetsCSVHeader) {
+				record.Metadata = row[5]
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+type groupExportCmdType struct {
+	client   sarama.Client
+	format   string
+	fileName string
+}
+
+func (g *groupExportCmdType) Run(cmd *cobra.Command, groups []string) error {
+	format, err := groupOffsetsFormat(g.format, g.fileName)
+	if err != nil {
+		return err
+	}
+	g.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer g.client.Close()
+	if len(groups) == 0 {
+		groups, err = kafkaadmin.ListGroups(g.client)
+		if err != nil {
+			return err
+		}
+	}
+	var records []groupOffsetRecord
+	for _, group := range groups {
+		offsets, err := kafkaadmin.GetGroupOffsets(g.client, group)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", group, err)
+		}
+		for topic, partitions := range offsets {
+			for partition, offset := range partitions {
+				timestamp, err := kafkaadmin.GetMessageTimestamp(g.client, topic, partition, offset.Offset)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "no timestamp for %s/%d: %v\n", topic, partition, err)
+					timestamp = -1
+				}
+				records = append(records, groupOffsetRecord{
+					Group:     group,
+					Topic:     topic,
+					Partition: partition,
+					Offset:    offset.Offset,
+					Timestamp: timestamp,
+					Metadata:  offset.Metadata})
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Group != records[j].Group {
+			return records[i].Group < records[j].Group
+		}
+		if records[i].Topic != records[j].Topic {
+			return records[i].Topic < records[j].Topic
+		}
+		return records[i].Partition < records[j].Partition
+	})
+	var writer io.Writer = os.Stdout
+	if len(g.fileName) > 0 {
+		file, err := os.Create(g.fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	return writeGroupOffsets(writer, format, records)
+}
+
+type groupImportCmdType struct {
+	client    sarama.Client
+	format    string
+	fileName  string
+	translate bool
+}
+
+func (g *groupImportCmdType) translateOffset(record groupOffsetRecord) (int64, error) {
+	if record.Timestamp < 0 {
+		return g.client.GetOffset(record.Topic, record.Partition, sarama.OffsetNewest)
+	}
+	offset, err := g.client.GetOffset(record.Topic, record.Partition, record.Timestamp)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		return g.client.GetOffset(record.Topic, record.Partition, sarama.OffsetNewest)
+	}
+	return offset, nil
+}
+
+func (g *groupImportCmdType) Run(cmd *cobra.Command, groups []string) error {
+	format, err := groupOffsetsFormat(g.format, g.fileName)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader = os.Stdin
+	if len(g.fileName) > 0 {
+		file, err := os.Open(g.fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	records, err := readGroupOffsets(reader, format)
+	if err != nil {
+		return err
+	}
+	g.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer g.client.Close()
+	offsets := make(map[string]kafkaadmin.GroupOffsets)
+	for _, record := range records {
+		if len(groups) > 0 && !inArray(record.Group, groups) {
+			continue
+		}
+		offset := record.Offset
+		if g.translate {
+			offset, err = g.translateOffset(record)
+			if err != nil {
+				return fmt.Errorf("translation failed for %s/%d: %w", record.Topic, record.Partition, err)
+			}
+		}
+		if offsets[record.Group] == nil {
+			offsets[record.Group] = make(kafkaadmin.GroupOffsets)
+		}
+		if offsets[record.Group][record.Topic] == nil {
+			offsets[record.Group][record.Topic] = make(map[int32]kafkaadmin.GroupOffset)
+		}
+		offsets[record.Group][record.Topic][record.Partition] = kafkaadmin.GroupOffset{Offset: offset, Metadata: record.Metadata}
+		fmt.Printf("%s %s/%d: %d -> %d\n", record.Group, record.Topic, record.Partition, record.Offset, offset)
+	}
+	for group, groupOffsets := range offsets {
+		err = kafkaadmin.CommitGroupOffsets(g.client, group, groupOffsets)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", group, err)
+		}
+	}
+	return nil
+}
+
+var groupExportCmd = &cobra.Command{
+	Use:     "export [groups]",
+	Aliases: []string{"e"},
+	Short:   "save committed offsets of consumer groups (all groups if none specified)"}
+
+var groupImportCmd = &cobra.Command{
+	Use:     "import [groups]",
+	Aliases: []string{"i"},
+	Short:   "restore committed offsets of consumer groups (all groups from the file if none specified)"}
+
+func init() {
+	var exportRunner groupExportCmdType
+	groupExportCmd.RunE = exportRunner.Run
+	flags := groupExportCmd.Flags()
+	flags.StringVarP(&exportRunner.fileName, "file", "f", "", "output file (stdout if not set)")
+	flags.StringVarP(&exportRunner.format, "format", "F", "", "output format: json or csv (detected by file extension if not set)")
+
+	var importRunner groupImportCmdType
+	groupImportCmd.RunE = importRunner.Run
+	flags = groupImportCmd.Flags()
+	flags.StringVarP(&importRunner.fileName, "file", "f", "", "input file (stdin if not set)")
+	flags.StringVarP(&importRunner.format, "format", "F", "", "input format: json or csv (detected by file extension if not set)")
+	flags.BoolVarP(&importRunner.translate, "translate", "t", false, "translate offsets using message timestamps (for a different cluster)")
+}
@@ -0,0 +1,118 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"strings"
+)
+
+type AclBinding = sarama.AclCreation
+
+// BindingFilter returns the filter matching exactly the binding.
+func BindingFilter(binding *AclBinding) *sarama.AclFilter {
+	name := binding.ResourceName
+	principal := binding.Principal
+	host := binding.Host
+	return &sarama.AclFilter{
+		Version:                   1,
+		ResourceType:              binding.ResourceType,
+		ResourceName:              &name,
+		ResourcePatternTypeFilter: binding.ResourcePatternType,
+		Principal:                 &principal,
+		Host:                      &host,
+		Operation:                 binding.Operation,
+		PermissionType:            binding.PermissionType}
+}
+
+func DescribeAcls(client sarama.Client, filter sarama.AclFilter) ([]*AclBinding, error) {
+	broker, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	filter.Version = 1
+	response, err := broker.DescribeAcls(&sarama.DescribeAclsRequest{Version: 1, AclFilter: filter})
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != sarama.ErrNoError {
+		if response.ErrMsg != nil {
+			return nil, fmt.Errorf("%w: %s", response.Err, *response.ErrMsg)
+		}
+		return nil, response.Err
+	}
+	var result []*AclBinding
+	for _, resource := range response.ResourceAcls {
+		for _, acl := range resource.Acls {
+			result = append(result, &AclBinding{Resource: resource.Resource, Acl: *acl})
+		}
+	}
+	return result, nil
+}
+
+func CreateAcls(client sarama.Client, bindings []*AclBinding) error {
+	broker, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	response, err := broker.CreateAcls(&sarama.CreateAclsRequest{Version: 1, AclCreations: bindings})
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for i, result := range response.AclCreationResponses {
+		if result.Err == sarama.ErrNoError {
+			continue
+		}
+		message := result.Err.Error()
+		if result.ErrMsg != nil {
+			message += ": " + *result.ErrMsg
+		}
+		if i < len(bindings) {
+			message = fmt.Sprintf("%s %s/%s %s %s: %s", bindings[i].Principal, bindings[i].ResourceType.String(), bindings[i].ResourceName,
+				bindings[i].PermissionType.String(), bindings[i].Operation.String(), message)
+		}
+		messages = append(messages, message)
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// DeleteAcls deletes the bindings matching the filters and returns them.
+func DeleteAcls(client sarama.Client, filters []*sarama.AclFilter) ([]*AclBinding, error) {
+	broker, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	for _, filter := range filters {
+		filter.Version = 1
+	}
+	response, err := broker.DeleteAcls(&sarama.DeleteAclsRequest{Version: 1, Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	var result []*AclBinding
+	var messages []string
+	for _, filterResponse := range response.FilterResponses {
+		if filterResponse.Err != sarama.ErrNoError {
+			message := filterResponse.Err.Error()
+			if filterResponse.ErrMsg != nil {
+				message += ": " + *filterResponse.ErrMsg
+			}
+			messages = append(messages, message)
+			continue
+		}
+		for _, matching := range filterResponse.MatchingAcls {
+			if matching.Err != sarama.ErrNoError {
+				messages = append(messages, matching.Err.Error())
+				continue
+			}
+			result = append(result, &AclBinding{Resource: matching.Resource, Acl: matching.Acl})
+		}
+	}
+	if len(messages) > 0 {
+		return result, fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return result, nil
+}
@@ -0,0 +1,219 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"sort"
+)
+
+type BrokerRack struct {
+	ID   int32
+	Rack string
+}
+
+func ClusterBrokers(client sarama.Client) []BrokerRack {
+	var result []BrokerRack
+	for _, broker := range client.Brokers() {
+		result = append(result, BrokerRack{ID: broker.ID(), Rack: broker.Rack()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+type assignmentBuilder struct {
+	brokers   []BrokerRack
+	racks     map[int32]string
+	rackCount int
+	rackAware bool
+	replicas  map[int32]int
+	leaders   map[int32]int
+}
+
+func newAssignmentBuilder(brokers []BrokerRack, rackAware bool) *assignmentBuilder {
+	result := &assignmentBuilder{
+		brokers:   brokers,
+		racks:     make(map[int32]string),
+		rackAware: rackAware,
+		replicas:  make(map[int32]int),
+		leaders:   make(map[int32]int)}
+	racks := make(map[string]bool)
+	for _, broker := range brokers {
+		result.racks[broker.ID] = broker.Rack
+		racks[broker.Rack] = true
+	}
+	result.rackCount = len(racks)
+	return result
+}
+
+func (b *assignmentBuilder) usesRack(replicas []int32, rack string) bool {
+	for _, replica := range replicas {
+		if b.racks[replica] == rack {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the least loaded broker not in replicas, preferring racks the partition does not use yet.
+// The first replica is the preferred leader so it is chosen by the number of leaders first.
+func (b *assignmentBuilder) pick(replicas []int32) (int32, bool) {
+	var best *BrokerRack
+	bestNewRack := false
+	for i := range b.brokers {
+		broker := &b.brokers[i]
+		if containsInt32(replicas, broker.ID) {
+			continue
+		}
+		newRack := !b.usesRack(replicas, broker.Rack)
+		if best == nil {
+			best, bestNewRack = broker, newRack
+			continue
+		}
+		if b.rackAware && newRack != bestNewRack {
+			if newRack {
+				best, bestNewRack = broker, newRack
+			}
+			continue
+		}
+		if len(replicas) == 0 && b.leaders[broker.ID] != b.leaders[best.ID] {
+			if b.leaders[broker.ID] < b.leaders[best.ID] {
+				best, bestNewRack = broker, newRack
+			}
+			continue
+		}
+		if b.replicas[broker.ID] < b.replicas[best.ID] {
+			best, bestNewRack = broker, newRack
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.ID, true
+}
+
+func (b *assignmentBuilder) add(replicas []int32, broker int32) []int32 {
+	if len(replicas) == 0 {
+		b.leaders[broker]++
+	}
+	b.replicas[broker]++
+	return append(replicas, broker)
+}
+
+func containsInt32(array []int32, target int32) bool {
+	for _, value := range array {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// BalanceAssignment keeps current replicas where possible: replicas on brokers outside the list, duplicate
+// racks (if rack-aware) and replicas over the replication factor or over the average broker load are moved to the
+// least loaded brokers.
+func BalanceAssignment(brokers []BrokerRack, current Assignment, replicationFactors map[string]int, rackAware bool) (Assignment, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no brokers to assign replicas to")
+	}
+	builder := newAssignmentBuilder(brokers, rackAware)
+	var keys []partitionKey
+	total := 0
+	for topic, partitions := range current {
+		replicationFactor := replicationFactors[topic]
+		if replicationFactor > len(brokers) {
+			return nil, fmt.Errorf("replication factor %d of %s is larger than the number of brokers %d", replicationFactor, topic, len(brokers))
+		}
+		for partition := range partitions {
+			keys = append(keys, partitionKey{topic, partition})
+			total += replicationFactor
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].topic != keys[j].topic {
+			return keys[i].topic < keys[j].topic
+		}
+		return keys[i].partition < keys[j].partition
+	})
+	maxLoad := (total + len(brokers) - 1) / len(brokers)
+	result := make(Assignment)
+	for _, key := range keys {
+		if result[key.topic] == nil {
+			result[key.topic] = make(map[int32][]int32)
+		}
+		var kept []int32
+		for _, replica := range current[key.topic][key.partition] {
+			if len(kept) >= replicationFactors[key.topic] {
+				break
+			}
+			if _, ok := builder.racks[replica]; !ok || containsInt32(kept, replica) {
+				continue
+			}
+			if rackAware && builder.usesRack(kept, builder.racks[replica]) && len(kept) < builder.rackCount {
+				continue
+			}
+			if builder.replicas[replica] >= maxLoad {
+				continue
+			}
+			kept = builder.add(kept, replica)
+		}
+		result[key.topic][key.partition] = kept
+	}
+	for _, key := range keys {
+		replicas := result[key.topic][key.partition]
+		for len(replicas) < replicationFactors[key.topic] {
+			broker, ok := builder.pick(replicas)
+			if !ok {
+				return nil, fmt.Errorf("not enough brokers for %s/%d", key.topic, key.partition)
+			}
+			replicas = builder.add(replicas, broker)
+		}
+		result[key.topic][key.partition] = replicas
+	}
+	return result, nil
+}
+
+// NewAssignments returns replicas of counts[topic] new partitions per topic, placed on the least loaded brokers
+// taking existing replicas into account.
+func NewAssignments(brokers []BrokerRack, existing Assignment, counts map[string]int, replicationFactors map[string]int, rackAware bool) (map[string][][]int32, error) {
+	builder := newAssignmentBuilder(brokers, rackAware)
+	for _, partitions := range existing {
+		for _, replicas := range partitions {
+			for i, replica := range replicas {
+				if i == 0 {
+					builder.leaders[replica]++
+				}
+				builder.replicas[replica]++
+			}
+		}
+	}
+	var topics []string
+	for topic := range counts {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	result := make(map[string][][]int32)
+	for _, topic := range topics {
+		if replicationFactors[topic] > len(brokers) {
+			return nil, fmt.Errorf("replication factor %d of %s is larger than the number of brokers %d", replicationFactors[topic], topic, len(brokers))
+		}
+		for i := 0; i < counts[topic]; i++ {
+			var replicas []int32
+			for len(replicas) < replicationFactors[topic] {
+				broker, ok := builder.pick(replicas)
+				if !ok {
+					return nil, fmt.Errorf("not enough brokers for a new partition of %s", topic)
+				}
+				replicas = builder.add(replicas, broker)
+			}
+			result[topic] = append(result[topic], replicas)
+		}
+	}
+	return result, nil
+}
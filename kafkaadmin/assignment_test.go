@@ -0,0 +1,159 @@
+package kafkaadmin
+
+import (
+	"testing"
+)
+
+func checkReplicas(t *testing.T, name string, brokers []BrokerRack, replicas []int32, replicationFactor int, rackAware bool) {
+	t.Helper()
+	racks := make(map[int32]string)
+	rackCount := make(map[string]bool)
+	for _, broker := range brokers {
+		racks[broker.ID] = broker.Rack
+		rackCount[broker.Rack] = true
+	}
+	if len(replicas) != replicationFactor {
+		t.Errorf("%s: %d replicas %v, expected %d", name, len(replicas), replicas, replicationFactor)
+	}
+	used := make(map[int32]bool)
+	usedRacks := make(map[string]bool)
+	for _, replica := range replicas {
+		if _, ok := racks[replica]; !ok {
+			t.Errorf("%s: replica %d is not on a listed broker", name, replica)
+		}
+		if used[replica] {
+			t.Errorf("%s: broker %d is used twice in %v", name, replica, replicas)
+		}
+		used[replica] = true
+		usedRacks[racks[replica]] = true
+	}
+	expectedRacks := replicationFactor
+	if len(rackCount) < expectedRacks {
+		expectedRacks = len(rackCount)
+	}
+	if rackAware && len(usedRacks) != expectedRacks {
+		t.Errorf("%s: replicas %v use %d racks, expected %d", name, replicas, len(usedRacks), expectedRacks)
+	}
+}
+
+func TestNewAssignments(t *testing.T) {
+	sixBrokers := []BrokerRack{{1, "a"}, {2, "a"}, {3, "b"}, {4, "b"}, {5, "c"}, {6, "c"}}
+	twoRacks := []BrokerRack{{1, "a"}, {2, "a"}, {3, "b"}, {4, "b"}}
+	for _, test := range []struct {
+		name              string
+		brokers           []BrokerRack
+		existing          Assignment
+		count             int
+		replicationFactor int
+		rackAware         bool
+		isError           bool
+	}{
+		{name: "rack-aware", brokers: sixBrokers, count: 6, replicationFactor: 3, rackAware: true},
+		{name: "not rack-aware", brokers: sixBrokers, count: 6, replicationFactor: 3},
+		{name: "fewer racks than replicas", brokers: twoRacks, count: 4, replicationFactor: 3, rackAware: true},
+		{name: "no racks", brokers: []BrokerRack{{1, ""}, {2, ""}, {3, ""}}, count: 3, replicationFactor: 2, rackAware: true},
+		{name: "existing replicas", brokers: sixBrokers, existing: Assignment{"other": {0: {1, 3, 5}, 1: {1, 3, 5}}}, count: 2, replicationFactor: 3, rackAware: true},
+		{name: "all brokers", brokers: twoRacks, count: 2, replicationFactor: 4, rackAware: true},
+		{name: "too few brokers", brokers: twoRacks, count: 1, replicationFactor: 5, isError: true},
+		{name: "no brokers", count: 1, replicationFactor: 1, isError: true},
+	} {
+		result, err := NewAssignments(test.brokers, test.existing, map[string]int{"topic": test.count},
+			map[string]int{"topic": test.replicationFactor}, test.rackAware)
+		if test.isError {
+			if err == nil {
+				t.Errorf("%s: error expected, got %v", test.name, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(result["topic"]) != test.count {
+			t.Errorf("%s: %d partitions, expected %d", test.name, len(result["topic"]), test.count)
+		}
+		leaders := make(map[int32]int)
+		for _, replicas := range result["topic"] {
+			checkReplicas(t, test.name, test.brokers, replicas, test.replicationFactor, test.rackAware)
+			if len(replicas) > 0 {
+				leaders[replicas[0]]++
+			}
+		}
+		if test.existing == nil && test.count == len(test.brokers) && len(leaders) != len(test.brokers) {
+			t.Errorf("%s: leaders %v are not spread over every broker", test.name, leaders)
+		}
+		for _, partitions := range test.existing {
+			for _, replicas := range partitions {
+				if leaders[replicas[0]] > 0 {
+					t.Errorf("%s: new leader on broker %d which leads existing partitions", test.name, replicas[0])
+				}
+			}
+		}
+	}
+}
+
+func TestBalanceAssignment(t *testing.T) {
+	sixBrokers := []BrokerRack{{1, "a"}, {2, "a"}, {3, "b"}, {4, "b"}, {5, "c"}, {6, "c"}}
+	for _, test := range []struct {
+		name              string
+		brokers           []BrokerRack
+		current           map[int32][]int32
+		replicationFactor int
+		rackAware         bool
+		kept              map[int32][]int32
+		isError           bool
+	}{
+		{name: "balanced is kept", brokers: sixBrokers, current: map[int32][]int32{0: {1, 3}, 1: {4, 6}, 2: {5, 2}},
+			replicationFactor: 2, rackAware: true, kept: map[int32][]int32{0: {1, 3}, 1: {4, 6}, 2: {5, 2}}},
+		{name: "removed broker", brokers: sixBrokers, current: map[int32][]int32{0: {1, 7}, 1: {7, 4}},
+			replicationFactor: 2, rackAware: true, kept: map[int32][]int32{0: {1}, 1: {4}}},
+		{name: "duplicate rack", brokers: sixBrokers, current: map[int32][]int32{0: {1, 2}},
+			replicationFactor: 2, rackAware: true, kept: map[int32][]int32{0: {1}}},
+		{name: "duplicate rack without rack awareness", brokers: sixBrokers, current: map[int32][]int32{0: {1, 2}},
+			replicationFactor: 2, kept: map[int32][]int32{0: {1, 2}}},
+		{name: "overloaded broker", brokers: sixBrokers, current: map[int32][]int32{0: {1}, 1: {1}, 2: {1}},
+			replicationFactor: 1, rackAware: true, kept: map[int32][]int32{0: {1}}},
+		{name: "replication factor increased", brokers: sixBrokers, current: map[int32][]int32{0: {1}, 1: {3}},
+			replicationFactor: 3, rackAware: true, kept: map[int32][]int32{0: {1}, 1: {3}}},
+		{name: "replication factor decreased", brokers: sixBrokers, current: map[int32][]int32{0: {1, 3, 5}},
+			replicationFactor: 1, rackAware: true, kept: map[int32][]int32{0: {1}}},
+		{name: "fewer racks than replicas", brokers: []BrokerRack{{1, "a"}, {2, "a"}, {3, "b"}}, current: map[int32][]int32{0: {1, 2}},
+			replicationFactor: 3, rackAware: true, kept: map[int32][]int32{0: {1, 2}}},
+		{name: "too few brokers", brokers: []BrokerRack{{1, "a"}, {2, "b"}}, current: map[int32][]int32{0: {1, 2}},
+			replicationFactor: 3, isError: true},
+		{name: "no brokers", current: map[int32][]int32{0: {1}}, replicationFactor: 1, isError: true},
+	} {
+		result, err := BalanceAssignment(test.brokers, Assignment{"topic": test.current},
+			map[string]int{"topic": test.replicationFactor}, test.rackAware)
+		if test.isError {
+			if err == nil {
+				t.Errorf("%s: error expected, got %v", test.name, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		load := make(map[int32]int)
+		for partition := range test.current {
+			replicas := result["topic"][partition]
+			checkReplicas(t, test.name, test.brokers, replicas, test.replicationFactor, test.rackAware)
+			for _, replica := range replicas {
+				load[replica]++
+			}
+			for _, replica := range test.kept[partition] {
+				if !containsInt32(replicas, replica) {
+					t.Errorf("%s: partition %d has replicas %v, expected %v to be kept", test.name, partition, replicas, test.kept[partition])
+					break
+				}
+			}
+		}
+		maxLoad := (len(test.current)*test.replicationFactor + len(test.brokers) - 1) / len(test.brokers)
+		for broker, count := range load {
+			if count > maxLoad {
+				t.Errorf("%s: broker %d has %d replicas, more than %d", test.name, broker, count, maxLoad)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"sort"
+)
+
+var ApiKeyNames = map[int16]string{
+	0:  "Produce",
+	1:  "Fetch",
+	2:  "ListOffsets",
+	3:  "Metadata",
+	4:  "LeaderAndIsr",
+	5:  "StopReplica",
+	6:  "UpdateMetadata",
+	7:  "ControlledShutdown",
+	8:  "OffsetCommit",
+	9:  "OffsetFetch",
+	10: "FindCoordinator",
+	11: "JoinGroup",
+	12: "Heartbeat",
+	13: "LeaveGroup",
+	14: "SyncGroup",
+	15: "DescribeGroups",
+	16: "ListGroups",
+	17: "SaslHandshake",
+	18: "ApiVersions",
+	19: "CreateTopics",
+	20: "DeleteTopics",
+	21: "DeleteRecords",
+	22: "InitProducerId",
+	23: "OffsetForLeaderEpoch",
+	24: "AddPartitionsToTxn",
+	25: "AddOffsetsToTxn",
+	26: "EndTxn",
+	27: "WriteTxnMarkers",
+	28: "TxnOffsetCommit",
+	29: "DescribeAcls",
+	30: "CreateAcls",
+	31: "DeleteAcls",
+	32: "DescribeConfigs",
+	33: "AlterConfigs",
+	34: "AlterReplicaLogDirs",
+	35: "DescribeLogDirs",
+	36: "SaslAuthenticate",
+	37: "CreatePartitions",
+	38: "CreateDelegationToken",
+	39: "RenewDelegationToken",
+	40: "ExpireDelegationToken",
+	41: "DescribeDelegationToken",
+	42: "DeleteGroups",
+	43: "ElectLeaders",
+	44: "IncrementalAlterConfigs",
+	45: "AlterPartitionReassignments",
+	46: "ListPartitionReassignments",
+	47: "OffsetDelete",
+	48: "DescribeClientQuotas",
+	49: "AlterClientQuotas",
+	50: "DescribeUserScramCredentials",
+	51: "AlterUserScramCredentials",
+	55: "DescribeQuorum",
+	56: "AlterPartition",
+	57: "UpdateFeatures",
+	58: "Envelope",
+	60: "DescribeCluster",
+	61: "DescribeProducers",
+	64: "UnregisterBroker",
+	65: "DescribeTransactions",
+	66: "ListTransactions",
+	67: "AllocateProducerIds",
+	68: "ConsumerGroupHeartbeat"}
+
+type ApiVersion struct {
+	Key        int16
+	Name       string
+	MinVersion int16
+	MaxVersion int16
+}
+
+type BrokerInfo struct {
+	ID          int32
+	Addr        string
+	Rack        string
+	Controller  bool
+	Partitions  int
+	Leaders     int
+	ApiVersions []ApiVersion
+}
+
+type ClusterInfo struct {
+	ClusterID    string
+	ControllerID int32
+	Brokers      []*BrokerInfo
+}
+
+func openBroker(client sarama.Client, broker *sarama.Broker) error {
+	err := broker.Open(client.Config())
+	if err != nil && err != sarama.ErrAlreadyConnected {
+		return err
+	}
+	_, err = broker.Connected()
+	return err
+}
+
+func GetMetadata(client sarama.Client, topics ...string) (*sarama.MetadataResponse, error) {
+	broker, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	return broker.GetMetadata(&sarama.MetadataRequest{Version: 5, Topics: topics})
+}
+
+// RequireApis checks that the controller supports the api keys.
+func RequireApis(client sarama.Client, keys ...int16) error {
+	broker, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	versions, err := GetApiVersions(client, broker)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		found := false
+		for _, version := range versions {
+			if version.Key == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("broker %d does not support %s", broker.ID(), ApiKeyNames[key])
+		}
+	}
+	return nil
+}
+
+func GetApiVersions(client sarama.Client, broker *sarama.Broker) ([]ApiVersion, error) {
+	err := openBroker(client, broker)
+	if err != nil {
+		return nil, err
+	}
+	response, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if sarama.KError(response.ErrorCode) != sarama.ErrNoError {
+		return nil, sarama.KError(response.ErrorCode)
+	}
+	var result []ApiVersion
+	for _, block := range response.ApiKeys {
+		name, ok := ApiKeyNames[block.ApiKey]
+		if !ok {
+			name = "Unknown"
+		}
+		result = append(result, ApiVersion{
+			Key:        block.ApiKey,
+			Name:       name,
+			MinVersion: block.MinVersion,
+			MaxVersion: block.MaxVersion})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+func DescribeCluster(client sarama.Client) (*ClusterInfo, error) {
+	metadata, err := GetMetadata(client)
+	if err != nil {
+		return nil, err
+	}
+	result := &ClusterInfo{ControllerID: metadata.ControllerID}
+	if metadata.ClusterID != nil {
+		result.ClusterID = *metadata.ClusterID
+	}
+	brokers := make(map[int32]*BrokerInfo)
+	for _, broker := range metadata.Brokers {
+		info := &BrokerInfo{
+			ID:         broker.ID(),
+			Addr:       broker.Addr(),
+			Rack:       broker.Rack(),
+			Controller: broker.ID() == metadata.ControllerID}
+		brokers[info.ID] = info
+		result.Brokers = append(result.Brokers, info)
+	}
+	for _, topic := range metadata.Topics {
+		for _, partition := range topic.Partitions {
+			for _, replica := range partition.Replicas {
+				if info, ok := brokers[replica]; ok {
+					info.Partitions++
+				}
+			}
+			if info, ok := brokers[partition.Leader]; ok {
+				info.Leaders++
+			}
+		}
+	}
+	for _, broker := range client.Brokers() {
+		info, ok := brokers[broker.ID()]
+		if !ok {
+			continue
+		}
+		info.ApiVersions, err = GetApiVersions(client, broker)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(result.Brokers, func(i, j int) bool {
+		return result.Brokers[i].ID < result.Brokers[j].ID
+	})
+	return result, nil
+}
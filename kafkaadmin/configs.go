@@ -0,0 +1,356 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ResourceConfigs = map[string]RawConfig
+
+type ConfigChanges = map[string]sarama.IncrementalAlterConfigsEntry
+
+const incrementalAlterConfigsKey = 44
+
+func findBroker(client sarama.Client, id int32) (*sarama.Broker, error) {
+	for _, broker := range client.Brokers() {
+		if broker.ID() == id {
+			return broker, openBroker(client, broker)
+		}
+	}
+	return nil, fmt.Errorf("broker %d not found", id)
+}
+
+// configBroker returns the broker a broker or broker logger resource belongs to, topics and the cluster-wide
+// broker default (empty name) are handled by any broker.
+func configBroker(client sarama.Client, resourceType sarama.ConfigResourceType, name string) (*sarama.Broker, error) {
+	if resourceType == sarama.BrokerLoggerResource || (resourceType == sarama.BrokerResource && len(name) > 0) {
+		id, err := strconv.ParseInt(name, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker id %s: %w", name, err)
+		}
+		return findBroker(client, int32(id))
+	}
+	return client.Controller()
+}
+
+func GetConfigs(client sarama.Client, resourceType sarama.ConfigResourceType, names ...string) (ResourceConfigs, error) {
+	requests := make(map[*sarama.Broker]*sarama.DescribeConfigsRequest)
+	for _, name := range names {
+		broker, err := configBroker(client, resourceType, name)
+		if err != nil {
+			return nil, err
+		}
+		request, ok := requests[broker]
+		if !ok {
+			request = &sarama.DescribeConfigsRequest{Version: 2, IncludeSynonyms: true}
+			requests[broker] = request
+		}
+		request.Resources = append(request.Resources, &sarama.ConfigResource{Type: resourceType, Name: name})
+	}
+	result := make(ResourceConfigs)
+	for broker, request := range requests {
+		response, err := broker.DescribeConfigs(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range response.Resources {
+			if resource.ErrorCode != int16(sarama.ErrNoError) {
+				return nil, sarama.KError(resource.ErrorCode)
+			}
+			configs := make(RawConfig)
+			for _, config := range resource.Configs {
+				configs[config.Name] = config
+			}
+			if len(configs) > 0 {
+				result[resource.Name] = configs
+			}
+		}
+	}
+	return result, nil
+}
+
+func IncrementalAlterConfigs(client sarama.Client, resourceType sarama.ConfigResourceType, changes ConfigChanges, validateOnly bool, names ...string) error {
+	requests := make(map[*sarama.Broker]*sarama.IncrementalAlterConfigsRequest)
+	for _, name := range names {
+		broker, err := configBroker(client, resourceType, name)
+		if err != nil {
+			return err
+		}
+		request, ok := requests[broker]
+		if !ok {
+			request = &sarama.IncrementalAlterConfigsRequest{ValidateOnly: validateOnly}
+			requests[broker] = request
+		}
+		request.Resources = append(request.Resources, &sarama.IncrementalAlterConfigsResource{
+			Type:          resourceType,
+			Name:          name,
+			ConfigEntries: changes})
+	}
+	errors := make(TopicErrors)
+	for broker, request := range requests {
+		response, err := broker.IncrementalAlterConfigs(request)
+		if err != nil {
+			return err
+		}
+		for _, resource := range response.Resources {
+			if resource != nil && sarama.KError(resource.ErrorCode) != sarama.ErrNoError {
+				errors[resource.Name] = sarama.KError(resource.ErrorCode)
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+func SetConfigChanges(set map[string]string, unset []string) ConfigChanges {
+	result := make(ConfigChanges)
+	for name, value := range set {
+		pvalue := new(string)
+		*pvalue = value
+		result[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: pvalue}
+	}
+	for _, name := range unset {
+		result[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+	}
+	return result
+}
+
+var (
+	incrementalSupportMutex sync.Mutex
+	incrementalSupport      = make(map[sarama.Client]bool)
+)
+
+// supportsIncrementalAlterConfigs asks the controller once per client whether IncrementalAlterConfigs is available.
+func supportsIncrementalAlterConfigs(client sarama.Client) (bool, error) {
+	incrementalSupportMutex.Lock()
+	defer incrementalSupportMutex.Unlock()
+	if supported, ok := incrementalSupport[client]; ok {
+		return supported, nil
+	}
+	broker, err := client.Controller()
+	if err != nil {
+		return false, err
+	}
+	versions, err := GetApiVersions(client, broker)
+	if err != nil {
+		return false, err
+	}
+	supported := false
+	for _, version := range versions {
+		if version.Key == incrementalAlterConfigsKey {
+			supported = true
+			break
+		}
+	}
+	incrementalSupport[client] = supported
+	return supported, nil
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func dynamicConfigSource(resourceType sarama.ConfigResourceType, name string) sarama.ConfigSource {
+	if resourceType == sarama.TopicResource {
+		return sarama.SourceTopic
+	}
+	if len(name) == 0 {
+		return sarama.SourceDynamicDefaultBroker
+	}
+	return sarama.SourceDynamicBroker
+}
+
+// MergeConfigs applies changes the way the broker does for IncrementalAlterConfigs, only the entries set on the
+// resource itself are kept.
+func MergeConfigs(current RawConfig, source sarama.ConfigSource, changes ConfigChanges) map[string]string {
+	result := make(map[string]string)
+	for name, config := range current {
+		if config.Source == source {
+			result[name] = config.Value
+		}
+	}
+	for name, change := range changes {
+		var value string
+		if change.Value != nil {
+			value = *change.Value
+		}
+		switch change.Operation {
+		case sarama.IncrementalAlterConfigsOperationSet:
+			result[name] = value
+		case sarama.IncrementalAlterConfigsOperationDelete:
+			delete(result, name)
+		case sarama.IncrementalAlterConfigsOperationAppend, sarama.IncrementalAlterConfigsOperationSubtract:
+			var items []string
+			if config, ok := current[name]; ok {
+				items = splitList(config.Value)
+			}
+			for _, item := range splitList(value) {
+				index := -1
+				for i := range items {
+					if items[i] == item {
+						index = i
+						break
+					}
+				}
+				if change.Operation == sarama.IncrementalAlterConfigsOperationAppend && index < 0 {
+					items = append(items, item)
+				}
+				if change.Operation == sarama.IncrementalAlterConfigsOperationSubtract && index >= 0 {
+					items = append(items[:index], items[index+1:]...)
+				}
+			}
+			result[name] = strings.Join(items, ",")
+		}
+	}
+	return result
+}
+
+// FallbackConfigValue returns the value a config falls back to when it is removed from the resource.
+func FallbackConfigValue(config *sarama.ConfigEntry, source sarama.ConfigSource) string {
+	for _, synonym := range config.Synonyms {
+		if synonym.Source != source {
+			return synonym.ConfigValue
+		}
+	}
+	return ""
+}
+
+// EffectiveConfigs returns the effective values of every config after applying changes.
+func EffectiveConfigs(current RawConfig, source sarama.ConfigSource, changes ConfigChanges) map[string]string {
+	merged := MergeConfigs(current, source, changes)
+	result := make(map[string]string)
+	for name, config := range current {
+		if value, ok := merged[name]; ok {
+			result[name] = value
+		} else if config.Source == source {
+			result[name] = FallbackConfigValue(config, source)
+		} else {
+			result[name] = config.Value
+		}
+	}
+	for name, value := range merged {
+		result[name] = value
+	}
+	return result
+}
+
+// checkSensitiveConfigs refuses dynamic sensitive configs not set by changes, brokers do not return their values
+// so the legacy request would remove them.
+func checkSensitiveConfigs(name string, current RawConfig, source sarama.ConfigSource, changes ConfigChanges) error {
+	var keys []string
+	for key, config := range current {
+		change, ok := changes[key]
+		if config.Sensitive && config.Source == source && (!ok || change.Operation != sarama.IncrementalAlterConfigsOperationSet) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return fmt.Errorf("%s has sensitive configs %s which the broker does not return, the legacy AlterConfigs would remove them", name, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// legacyAlterConfigs does read-merge-write for brokers without IncrementalAlterConfigs, the legacy request
+// replaces the whole resource config.
+func legacyAlterConfigs(client sarama.Client, resourceType sarama.ConfigResourceType, changes ConfigChanges, validateOnly bool, names ...string) error {
+	if resourceType == sarama.BrokerLoggerResource {
+		return fmt.Errorf("broker loggers can only be changed with IncrementalAlterConfigs")
+	}
+	current, err := GetConfigs(client, resourceType, names...)
+	if err != nil {
+		return err
+	}
+	requests := make(map[*sarama.Broker]*sarama.AlterConfigsRequest)
+	for _, name := range names {
+		broker, err := configBroker(client, resourceType, name)
+		if err != nil {
+			return err
+		}
+		request, ok := requests[broker]
+		if !ok {
+			request = &sarama.AlterConfigsRequest{ValidateOnly: validateOnly}
+			requests[broker] = request
+		}
+		source := dynamicConfigSource(resourceType, name)
+		err = checkSensitiveConfigs(name, current[name], source, changes)
+		if err != nil {
+			return err
+		}
+		resource := &sarama.AlterConfigsResource{
+			Type:          resourceType,
+			Name:          name,
+			ConfigEntries: make(map[string]*string)}
+		for key, value := range MergeConfigs(current[name], source, changes) {
+			pvalue := new(string)
+			*pvalue = value
+			resource.ConfigEntries[key] = pvalue
+		}
+		request.Resources = append(request.Resources, resource)
+	}
+	errors := make(TopicErrors)
+	for broker, request := range requests {
+		response, err := broker.AlterConfigs(request)
+		if err != nil {
+			return err
+		}
+		for _, resource := range response.Resources {
+			if resource != nil && sarama.KError(resource.ErrorCode) != sarama.ErrNoError {
+				errors[resource.Name] = sarama.KError(resource.ErrorCode)
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// AlterConfigs uses IncrementalAlterConfigs when the cluster supports it and falls back to read-merge-write
+// otherwise.
+func AlterConfigs(client sarama.Client, resourceType sarama.ConfigResourceType, changes ConfigChanges, validateOnly bool, names ...string) error {
+	supported, err := supportsIncrementalAlterConfigs(client)
+	if err != nil {
+		return err
+	}
+	if supported {
+		return IncrementalAlterConfigs(client, resourceType, changes, validateOnly, names...)
+	}
+	return legacyAlterConfigs(client, resourceType, changes, validateOnly, names...)
+}
+
+// DynamicConfigs returns the values set on the resources themselves (every level for broker loggers), sensitive
+// values are skipped as brokers do not return them.
+func DynamicConfigs(client sarama.Client, resourceType sarama.ConfigResourceType, names ...string) (map[string]map[string]string, error) {
+	configs, err := GetConfigs(client, resourceType, names...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string)
+	for name, raw := range configs {
+		result[name] = make(map[string]string)
+		source := dynamicConfigSource(resourceType, name)
+		for key, config := range raw {
+			if config.Sensitive {
+				continue
+			}
+			if resourceType == sarama.BrokerLoggerResource || config.Source == source {
+				result[name][key] = config.Value
+			}
+		}
+	}
+	return result, nil
+}
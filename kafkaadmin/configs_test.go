@@ -0,0 +1,71 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+	"reflect"
+	"testing"
+)
+
+func configChange(operation sarama.IncrementalAlterConfigsOperation, value string) sarama.IncrementalAlterConfigsEntry {
+	return sarama.IncrementalAlterConfigsEntry{Operation: operation, Value: &value}
+}
+
+func TestMergeConfigs(t *testing.T) {
+	current := RawConfig{
+		"cleanup.policy":    {Name: "cleanup.policy", Value: "delete", Source: sarama.SourceTopic},
+		"retention.ms":      {Name: "retention.ms", Value: "1000", Source: sarama.SourceTopic},
+		"segment.bytes":     {Name: "segment.bytes", Value: "1024", Source: sarama.SourceDefault, Default: true},
+		"follower.replicas": {Name: "follower.replicas", Value: "0:1,1:2", Source: sarama.SourceTopic}}
+	for _, test := range []struct {
+		name     string
+		changes  ConfigChanges
+		expected map[string]string
+	}{
+		{name: "no changes", changes: ConfigChanges{},
+			expected: map[string]string{"cleanup.policy": "delete", "retention.ms": "1000", "follower.replicas": "0:1,1:2"}},
+		{name: "set", changes: ConfigChanges{"retention.ms": configChange(sarama.IncrementalAlterConfigsOperationSet, "2000"),
+			"segment.bytes": configChange(sarama.IncrementalAlterConfigsOperationSet, "2048")},
+			expected: map[string]string{"cleanup.policy": "delete", "retention.ms": "2000", "segment.bytes": "2048", "follower.replicas": "0:1,1:2"}},
+		{name: "delete", changes: ConfigChanges{"retention.ms": {Operation: sarama.IncrementalAlterConfigsOperationDelete}},
+			expected: map[string]string{"cleanup.policy": "delete", "follower.replicas": "0:1,1:2"}},
+		{name: "append", changes: ConfigChanges{"cleanup.policy": configChange(sarama.IncrementalAlterConfigsOperationAppend, "compact")},
+			expected: map[string]string{"cleanup.policy": "delete,compact", "retention.ms": "1000", "follower.replicas": "0:1,1:2"}},
+		{name: "append existing item", changes: ConfigChanges{"follower.replicas": configChange(sarama.IncrementalAlterConfigsOperationAppend, "1:2, 2:3")},
+			expected: map[string]string{"cleanup.policy": "delete", "retention.ms": "1000", "follower.replicas": "0:1,1:2,2:3"}},
+		{name: "append to missing", changes: ConfigChanges{"leader.replicas": configChange(sarama.IncrementalAlterConfigsOperationAppend, "0:1,0:2")},
+			expected: map[string]string{"cleanup.policy": "delete", "retention.ms": "1000", "follower.replicas": "0:1,1:2", "leader.replicas": "0:1,0:2"}},
+		{name: "subtract", changes: ConfigChanges{"follower.replicas": configChange(sarama.IncrementalAlterConfigsOperationSubtract, "0:1,3:4")},
+			expected: map[string]string{"cleanup.policy": "delete", "retention.ms": "1000", "follower.replicas": "1:2"}},
+		{name: "subtract every item", changes: ConfigChanges{"cleanup.policy": configChange(sarama.IncrementalAlterConfigsOperationSubtract, "delete")},
+			expected: map[string]string{"cleanup.policy": "", "retention.ms": "1000", "follower.replicas": "0:1,1:2"}},
+	} {
+		result := MergeConfigs(current, sarama.SourceTopic, test.changes)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestCheckSensitiveConfigs(t *testing.T) {
+	password := "secret"
+	current := RawConfig{
+		"retention.ms":            {Name: "retention.ms", Value: "1000", Source: sarama.SourceDynamicBroker},
+		"ssl.keystore.password":   {Name: "ssl.keystore.password", Source: sarama.SourceDynamicBroker, Sensitive: true},
+		"sasl.jaas.config":        {Name: "sasl.jaas.config", Source: sarama.SourceStaticBroker, Sensitive: true},
+		"ssl.truststore.password": {Name: "ssl.truststore.password", Source: sarama.SourceDynamicDefaultBroker, Sensitive: true}}
+	for _, test := range []struct {
+		name    string
+		changes ConfigChanges
+		isError bool
+	}{
+		{name: "untouched sensitive config", changes: ConfigChanges{"retention.ms": configChange(sarama.IncrementalAlterConfigsOperationSet, "2000")}, isError: true},
+		{name: "no changes", changes: ConfigChanges{}, isError: true},
+		{name: "sensitive config deleted", changes: ConfigChanges{"ssl.keystore.password": {Operation: sarama.IncrementalAlterConfigsOperationDelete}}, isError: true},
+		{name: "sensitive config set", changes: ConfigChanges{"ssl.keystore.password": {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &password}}},
+	} {
+		err := checkSensitiveConfigs("1", current, sarama.SourceDynamicBroker, test.changes)
+		if test.isError != (err != nil) {
+			t.Errorf("%s: got error %v, expected error %v", test.name, err, test.isError)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package kafkaadmin
 
-import "github.com/Shopify/sarama"
+import (
+	"github.com/IBM/sarama"
+	"time"
+)
 
 func NewConfig() *sarama.Config {
 	conf := sarama.NewConfig()
@@ -12,34 +15,19 @@ func NewDefaultClient(addrs []string) (sarama.Client, error) {
 	return sarama.NewClient(addrs, NewConfig())
 }
 
+// NewVersionedClient is for APIs sarama refuses to send with the default version (quotas, SCRAM credentials).
+func NewVersionedClient(addrs []string, version sarama.KafkaVersion) (sarama.Client, error) {
+	conf := NewConfig()
+	conf.Version = version
+	return sarama.NewClient(addrs, conf)
+}
+
 type RawConfig = map[string]*sarama.ConfigEntry
 
 type TopicsConfigs = map[string]RawConfig
 
 func GetTopicConfigs(client sarama.Client, topics ...string) (TopicsConfigs, error) {
-	request := sarama.DescribeConfigsRequest{Version: 2}
-	for _, topic := range topics {
-		request.Resources = append(request.Resources, &sarama.ConfigResource{Type: sarama.TopicResource, Name: topic})
-	}
-	broker, err := client.Controller()
-	if err != nil {
-		return nil, err
-	}
-	response, err := broker.DescribeConfigs(&request)
-	result := make(TopicsConfigs)
-	for _, resource := range response.Resources {
-		if resource.ErrorCode != int16(sarama.ErrNoError) {
-			return nil, sarama.KError(resource.ErrorCode)
-		}
-		configs := make(RawConfig)
-		for _, config := range resource.Configs {
-			configs[config.Name] = config
-		}
-		if len(configs) > 0 {
-			result[resource.Name] = configs
-		}
-	}
-	return result, nil
+	return GetConfigs(client, sarama.TopicResource, topics...)
 }
 
 func GetTopicRange(client sarama.Client, topic string, partition int32) (min int64, max int64, err error) {
@@ -53,3 +41,52 @@ func GetTopicRange(client sarama.Client, topic string, partition int32) (min int
 	}
 	return min, max, err
 }
+
+// GetMessageTimestamp returns the timestamp (in milliseconds) of the first message at or after offset, -1 if
+// there is none.
+func GetMessageTimestamp(client sarama.Client, topic string, partition int32, offset int64) (int64, error) {
+	broker, err := client.Leader(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	request := &sarama.FetchRequest{Version: 4, MaxWaitTime: 500, MinBytes: 1, MaxBytes: 1024 * 1024}
+	request.AddBlock(topic, partition, offset, 1024*1024, -1)
+	response, err := broker.Fetch(request)
+	if err != nil {
+		return 0, err
+	}
+	block := response.GetBlock(topic, partition)
+	if block == nil {
+		return -1, nil
+	}
+	if block.Err != sarama.ErrNoError {
+		return 0, block.Err
+	}
+	for _, records := range block.RecordsSet {
+		if records.RecordBatch != nil {
+			batch := records.RecordBatch
+			if batch.Control {
+				continue
+			}
+			for _, record := range batch.Records {
+				if batch.FirstOffset+record.OffsetDelta < offset {
+					continue
+				}
+				if batch.LogAppendTime {
+					return batch.MaxTimestamp.UnixNano() / int64(time.Millisecond), nil
+				}
+				return batch.FirstTimestamp.Add(record.TimestampDelta).UnixNano() / int64(time.Millisecond), nil
+			}
+		}
+		if records.MsgSet != nil {
+			for _, message := range records.MsgSet.Messages {
+				for _, inner := range message.Messages() {
+					if inner.Offset >= offset {
+						return inner.Msg.Timestamp.UnixNano() / int64(time.Millisecond), nil
+					}
+				}
+			}
+		}
+	}
+	return -1, nil
+}
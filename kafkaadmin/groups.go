@@ -0,0 +1,142 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+	"sort"
+)
+
+type GroupOffset struct {
+	Offset   int64
+	Metadata string
+}
+
+type GroupOffsets = map[string]map[int32]GroupOffset
+
+func ListGroups(client sarama.Client) ([]string, error) {
+	var result []string
+	for _, broker := range client.Brokers() {
+		err := openBroker(client, broker)
+		if err != nil {
+			return nil, err
+		}
+		response, err := broker.ListGroups(&sarama.ListGroupsRequest{})
+		if err != nil {
+			return nil, err
+		}
+		if response.Err != sarama.ErrNoError {
+			return nil, response.Err
+		}
+		for group := range response.Groups {
+			result = append(result, group)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func GetGroupOffsets(client sarama.Client, group string) (GroupOffsets, error) {
+	broker, err := client.Coordinator(group)
+	if err != nil {
+		return nil, err
+	}
+	//version 2 and above returns every committed partition when no partitions are given
+	response, err := broker.FetchOffset(&sarama.OffsetFetchRequest{Version: 5, ConsumerGroup: group})
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != sarama.ErrNoError {
+		return nil, response.Err
+	}
+	result := make(GroupOffsets)
+	for topic, partitions := range response.Blocks {
+		for partition, block := range partitions {
+			if block.Err != sarama.ErrNoError {
+				return nil, block.Err
+			}
+			if block.Offset < 0 {
+				continue
+			}
+			if result[topic] == nil {
+				result[topic] = make(map[int32]GroupOffset)
+			}
+			result[topic][partition] = GroupOffset{Offset: block.Offset, Metadata: block.Metadata}
+		}
+	}
+	return result, nil
+}
+
+// CommitGroupOffsets commits the offsets of a group without active members, otherwise the coordinator rejects
+// the commit.
+func CommitGroupOffsets(client sarama.Client, group string, offsets GroupOffsets) error {
+	broker, err := client.Coordinator(group)
+	if err != nil {
+		return err
+	}
+	request := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: -1,
+		RetentionTime:           -1}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			request.AddBlock(topic, partition, offset.Offset, 0, offset.Metadata)
+		}
+	}
+	response, err := broker.CommitOffset(request)
+	if err != nil {
+		return err
+	}
+	errors := make(PartitionErrors)
+	for topic, partitions := range response.Errors {
+		for partition, value := range partitions {
+			if value != sarama.ErrNoError {
+				errors.add(topic, partition, value)
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// ActiveGroupTopics returns consumer groups with members by topic assigned to the members.
+func ActiveGroupTopics(client sarama.Client) (map[string][]string, error) {
+	groups, err := ListGroups(client)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	for _, group := range groups {
+		broker, err := client.Coordinator(group)
+		if err != nil {
+			return nil, err
+		}
+		response, err := broker.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: []string{group}})
+		if err != nil {
+			return nil, err
+		}
+		for _, description := range response.Groups {
+			if description.Err != sarama.ErrNoError {
+				return nil, description.Err
+			}
+			if description.ProtocolType != "consumer" {
+				continue
+			}
+			topics := make(map[string]bool)
+			for _, member := range description.Members {
+				assignment, err := member.GetMemberAssignment()
+				if err != nil || assignment == nil {
+					continue
+				}
+				for topic := range assignment.Topics {
+					topics[topic] = true
+				}
+			}
+			for topic := range topics {
+				result[topic] = append(result[topic], group)
+			}
+		}
+	}
+	return result, nil
+}
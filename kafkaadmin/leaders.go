@@ -0,0 +1,73 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+)
+
+type LeaderStats struct {
+	Broker    int32
+	Replicas  int
+	Leaders   int
+	Preferred int
+}
+
+// GetLeaderStats counts leaders, replicas and preferred leaders (first replicas) per broker, brokers without
+// partitions are included.
+func GetLeaderStats(client sarama.Client, metadata *sarama.MetadataResponse) map[int32]*LeaderStats {
+	result := make(map[int32]*LeaderStats)
+	for _, broker := range client.Brokers() {
+		result[broker.ID()] = &LeaderStats{Broker: broker.ID()}
+	}
+	stats := func(id int32) *LeaderStats {
+		if result[id] == nil {
+			result[id] = &LeaderStats{Broker: id}
+		}
+		return result[id]
+	}
+	for _, topic := range metadata.Topics {
+		for _, partition := range topic.Partitions {
+			if partition.Leader >= 0 {
+				stats(partition.Leader).Leaders++
+			}
+			for i, replica := range partition.Replicas {
+				stats(replica).Replicas++
+				if i == 0 {
+					stats(replica).Preferred++
+				}
+			}
+		}
+	}
+	return result
+}
+
+// ElectLeaders does not report partitions which do not need an election as errors.
+func ElectLeaders(client sarama.Client, electionType sarama.ElectionType, partitions TopicPartitions) error {
+	controller, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	request := &sarama.ElectLeadersRequest{
+		Version:         2,
+		Type:            electionType,
+		TopicPartitions: partitions,
+		TimeoutMs:       int32(client.Config().Admin.Timeout.Milliseconds())}
+	response, err := controller.ElectLeaders(request)
+	if err != nil {
+		return err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		return response.ErrorCode
+	}
+	errors := make(PartitionErrors)
+	for topic, results := range response.ReplicaElectionResults {
+		for partition, result := range results {
+			if result.ErrorCode != sarama.ErrNoError && result.ErrorCode != sarama.ErrElectionNotNeeded {
+				errors.add(topic, partition, result.ErrorCode)
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
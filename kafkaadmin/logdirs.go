@@ -0,0 +1,179 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+	"sort"
+)
+
+const alterReplicaLogDirsKey = 34
+
+type ReplicaDir struct {
+	Broker    int32
+	Path      string
+	Topic     string
+	Partition int32
+	Size      int64
+	OffsetLag int64
+	IsFuture  bool
+}
+
+type LogDir struct {
+	Broker   int32
+	Path     string
+	Err      sarama.KError
+	Replicas []*ReplicaDir
+}
+
+func (l *LogDir) Size() int64 {
+	var result int64
+	for _, replica := range l.Replicas {
+		result += replica.Size
+	}
+	return result
+}
+
+// DescribeLogDirs queries every broker, all topics are described if none are given.
+func DescribeLogDirs(client sarama.Client, topics ...string) ([]*LogDir, error) {
+	request := &sarama.DescribeLogDirsRequest{Version: 1}
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, err
+		}
+		request.DescribeTopics = append(request.DescribeTopics, sarama.DescribeLogDirsRequestTopic{Topic: topic, PartitionIDs: partitions})
+	}
+	var result []*LogDir
+	for _, broker := range client.Brokers() {
+		err := openBroker(client, broker)
+		if err != nil {
+			return nil, err
+		}
+		response, err := broker.DescribeLogDirs(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range response.LogDirs {
+			logDir := &LogDir{Broker: broker.ID(), Path: dir.Path, Err: dir.ErrorCode}
+			for _, topic := range dir.Topics {
+				for _, partition := range topic.Partitions {
+					logDir.Replicas = append(logDir.Replicas, &ReplicaDir{
+						Broker:    broker.ID(),
+						Path:      dir.Path,
+						Topic:     topic.Topic,
+						Partition: partition.PartitionID,
+						Size:      partition.Size,
+						OffsetLag: partition.OffsetLag,
+						IsFuture:  partition.IsTemporary})
+				}
+			}
+			result = append(result, logDir)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Broker != result[j].Broker {
+			return result[i].Broker < result[j].Broker
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// TopicSizes counts the size of a partition as the size of its largest replica, future replicas being moved
+// between dirs are not counted.
+func TopicSizes(logDirs []*LogDir) map[string]int64 {
+	partitions := make(map[string]map[int32]int64)
+	for _, dir := range logDirs {
+		for _, replica := range dir.Replicas {
+			if replica.IsFuture {
+				continue
+			}
+			if partitions[replica.Topic] == nil {
+				partitions[replica.Topic] = make(map[int32]int64)
+			}
+			if replica.Size > partitions[replica.Topic][replica.Partition] {
+				partitions[replica.Topic][replica.Partition] = replica.Size
+			}
+		}
+	}
+	result := make(map[string]int64)
+	for topic, sizes := range partitions {
+		for _, size := range sizes {
+			result[topic] += size
+		}
+	}
+	return result
+}
+
+type ReplicaMove struct {
+	Broker    int32
+	Topic     string
+	Partition int32
+	Path      string
+}
+
+func encodeAlterReplicaLogDirs(moves []ReplicaMove) []byte {
+	dirs := make(map[string]TopicPartitions)
+	var paths []string
+	for _, move := range moves {
+		if dirs[move.Path] == nil {
+			dirs[move.Path] = make(TopicPartitions)
+			paths = append(paths, move.Path)
+		}
+		dirs[move.Path][move.Topic] = append(dirs[move.Path][move.Topic], move.Partition)
+	}
+	var encoder rawEncoder
+	encoder.putInt32(int32(len(paths)))
+	for _, path := range paths {
+		encoder.putString(path)
+		encoder.putInt32(int32(len(dirs[path])))
+		for topic, partitions := range dirs[path] {
+			encoder.putString(topic)
+			encoder.putInt32(int32(len(partitions)))
+			for _, partition := range partitions {
+				encoder.putInt32(partition)
+			}
+		}
+	}
+	return encoder.Bytes()
+}
+
+// AlterReplicaLogDirs moves replicas to other log dirs of their brokers, the data is copied to a future replica
+// in the background. Sarama has no AlterReplicaLogDirs, version 1 is encoded here.
+func AlterReplicaLogDirs(client sarama.Client, moves []ReplicaMove) error {
+	byBroker := make(map[int32][]ReplicaMove)
+	for _, move := range moves {
+		byBroker[move.Broker] = append(byBroker[move.Broker], move)
+	}
+	errors := make(PartitionErrors)
+	for id, brokerMoves := range byBroker {
+		broker, err := findBroker(client, id)
+		if err != nil {
+			return err
+		}
+		response, err := sendRawRequest(client, broker, alterReplicaLogDirsKey, 1, encodeAlterReplicaLogDirs(brokerMoves))
+		if err != nil {
+			return err
+		}
+		decoder := &rawDecoder{data: response}
+		decoder.getInt32()
+		topics := decoder.getInt32()
+		for i := int32(0); i < topics && decoder.err == nil; i++ {
+			topic := decoder.getString()
+			partitions := decoder.getInt32()
+			for j := int32(0); j < partitions && decoder.err == nil; j++ {
+				partition := decoder.getInt32()
+				code := sarama.KError(decoder.getInt16())
+				if code != sarama.ErrNoError {
+					errors.add(topic, partition, code)
+				}
+			}
+		}
+		if decoder.err != nil {
+			return decoder.err
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+	"sync"
+)
+
+type OffsetRange struct {
+	Oldest int64
+	Newest int64
+}
+
+type TopicRanges = map[string]map[int32]OffsetRange
+
+type TopicPartitions = map[string][]int32
+
+func groupByLeader(client sarama.Client, partitions TopicPartitions) (map[*sarama.Broker]TopicPartitions, PartitionErrors) {
+	result := make(map[*sarama.Broker]TopicPartitions)
+	errors := make(PartitionErrors)
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			broker, err := client.Leader(topic, partition)
+			if err != nil {
+				errors.add(topic, partition, sarama.ErrLeaderNotAvailable)
+				continue
+			}
+			if result[broker] == nil {
+				result[broker] = make(TopicPartitions)
+			}
+			result[broker][topic] = append(result[broker][topic], partition)
+		}
+	}
+	return result, errors
+}
+
+func getBrokerOffsets(broker *sarama.Broker, partitions TopicPartitions, time int64) (map[string]map[int32]int64, PartitionErrors, error) {
+	request := &sarama.OffsetRequest{Version: 1}
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			request.AddBlock(topic, partition, time, 1)
+		}
+	}
+	response, err := broker.GetAvailableOffsets(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make(map[string]map[int32]int64)
+	errors := make(PartitionErrors)
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			block := response.GetBlock(topic, partition)
+			if block == nil {
+				errors.add(topic, partition, sarama.ErrUnknownTopicOrPartition)
+				continue
+			}
+			if block.Err != sarama.ErrNoError {
+				errors.add(topic, partition, block.Err)
+				continue
+			}
+			if result[topic] == nil {
+				result[topic] = make(map[int32]int64)
+			}
+			result[topic][partition] = block.Offset
+		}
+	}
+	return result, errors, nil
+}
+
+func getBrokerRanges(broker *sarama.Broker, partitions TopicPartitions) (TopicRanges, PartitionErrors, error) {
+	oldest, errors, err := getBrokerOffsets(broker, partitions, sarama.OffsetOldest)
+	if err != nil {
+		return nil, nil, err
+	}
+	newest, newestErrors, err := getBrokerOffsets(broker, partitions, sarama.OffsetNewest)
+	if err != nil {
+		return nil, nil, err
+	}
+	errors.merge(newestErrors)
+	result := make(TopicRanges)
+	for topic, offsets := range oldest {
+		for partition, offset := range offsets {
+			max, ok := newest[topic][partition]
+			if !ok {
+				continue
+			}
+			if result[topic] == nil {
+				result[topic] = make(map[int32]OffsetRange)
+			}
+			result[topic][partition] = OffsetRange{Oldest: offset, Newest: max}
+		}
+	}
+	return result, errors, nil
+}
+
+// GetTopicRanges sends one ListOffsets request per leader for the oldest and one for the newest offsets, brokers
+// are queried concurrently. progress is called with the number of partitions done so far, ranges are returned even
+// if some partitions failed.
+func GetTopicRanges(client sarama.Client, partitions TopicPartitions, progress func(done int, total int)) (TopicRanges, error) {
+	byLeader, errors := groupByLeader(client, partitions)
+	total := 0
+	for _, ids := range partitions {
+		total += len(ids)
+	}
+	result := make(TopicRanges)
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	var requestErr error
+	done := 0
+	for broker, brokerPartitions := range byLeader {
+		wait.Add(1)
+		go func(broker *sarama.Broker, brokerPartitions TopicPartitions) {
+			defer wait.Done()
+			ranges, rangeErrors, err := getBrokerRanges(broker, brokerPartitions)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				requestErr = err
+			} else {
+				for topic, partitionRanges := range ranges {
+					if result[topic] == nil {
+						result[topic] = make(map[int32]OffsetRange)
+					}
+					for partition, offsetRange := range partitionRanges {
+						result[topic][partition] = offsetRange
+					}
+				}
+				errors.merge(rangeErrors)
+			}
+			for _, ids := range brokerPartitions {
+				done += len(ids)
+			}
+			if progress != nil {
+				progress(done, total)
+			}
+		}(broker, brokerPartitions)
+	}
+	wait.Wait()
+	if requestErr != nil {
+		return result, requestErr
+	}
+	if len(errors) > 0 {
+		return result, errors
+	}
+	return result, nil
+}
+
+// DeleteRecords deletes records before the given offsets, requests are sent to partition leaders. It returns
+// the new low watermarks.
+func DeleteRecords(client sarama.Client, offsets map[string]map[int32]int64) (map[string]map[int32]int64, error) {
+	partitions := make(TopicPartitions)
+	for topic, partitionOffsets := range offsets {
+		for partition := range partitionOffsets {
+			partitions[topic] = append(partitions[topic], partition)
+		}
+	}
+	byLeader, errors := groupByLeader(client, partitions)
+	result := make(map[string]map[int32]int64)
+	for broker, brokerPartitions := range byLeader {
+		request := &sarama.DeleteRecordsRequest{Topics: make(map[string]*sarama.DeleteRecordsRequestTopic), Timeout: client.Config().Admin.Timeout}
+		for topic, ids := range brokerPartitions {
+			request.Topics[topic] = &sarama.DeleteRecordsRequestTopic{PartitionOffsets: make(map[int32]int64)}
+			for _, partition := range ids {
+				request.Topics[topic].PartitionOffsets[partition] = offsets[topic][partition]
+			}
+		}
+		response, err := broker.DeleteRecords(request)
+		if err != nil {
+			return result, err
+		}
+		for topic, ids := range brokerPartitions {
+			for _, partition := range ids {
+				var block *sarama.DeleteRecordsResponsePartition
+				if response.Topics[topic] != nil {
+					block = response.Topics[topic].Partitions[partition]
+				}
+				if block == nil {
+					errors.add(topic, partition, sarama.ErrUnknownTopicOrPartition)
+					continue
+				}
+				if block.Err != sarama.ErrNoError {
+					errors.add(topic, partition, block.Err)
+					continue
+				}
+				if result[topic] == nil {
+					result[topic] = make(map[int32]int64)
+				}
+				result[topic][partition] = block.LowWatermark
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return result, errors
+	}
+	return result, nil
+}
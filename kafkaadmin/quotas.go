@@ -0,0 +1,138 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"sort"
+	"strings"
+)
+
+const (
+	DescribeClientQuotasKey = 48
+	AlterClientQuotasKey    = 49
+)
+
+// QuotaEntity is a user, a client id or both, nil means the component is absent, an empty name is the default
+// entity.
+type QuotaEntity struct {
+	User     *string
+	ClientID *string
+}
+
+func (q QuotaEntity) components() []sarama.QuotaEntityComponent {
+	var result []sarama.QuotaEntityComponent
+	for _, item := range []struct {
+		entityType sarama.QuotaEntityType
+		name       *string
+	}{{sarama.QuotaEntityUser, q.User}, {sarama.QuotaEntityClientID, q.ClientID}} {
+		if item.name == nil {
+			continue
+		}
+		component := sarama.QuotaEntityComponent{EntityType: item.entityType, MatchType: sarama.QuotaMatchExact, Name: *item.name}
+		if len(*item.name) == 0 {
+			component.MatchType = sarama.QuotaMatchDefault
+		}
+		result = append(result, component)
+	}
+	return result
+}
+
+func (q QuotaEntity) String() string {
+	var parts []string
+	for _, item := range []struct {
+		entityType string
+		name       *string
+	}{{"user", q.User}, {"client-id", q.ClientID}} {
+		if item.name == nil {
+			continue
+		}
+		name := *item.name
+		if len(name) == 0 {
+			name = "<default>"
+		}
+		parts = append(parts, item.entityType+"="+name)
+	}
+	return strings.Join(parts, ",")
+}
+
+type ClientQuota struct {
+	Entity QuotaEntity
+	Values map[string]float64
+}
+
+// DescribeClientQuotas returns every entity with the given components without an exact match, a component that
+// is not set matches anything unless isStrict.
+func DescribeClientQuotas(client sarama.Client, entity QuotaEntity, isStrict bool) ([]*ClientQuota, error) {
+	var filter []sarama.QuotaFilterComponent
+	for _, component := range entity.components() {
+		filter = append(filter, sarama.QuotaFilterComponent{EntityType: component.EntityType, MatchType: component.MatchType, Match: component.Name})
+	}
+	broker, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	response, err := broker.DescribeClientQuotas(&sarama.DescribeClientQuotasRequest{Components: filter, Strict: isStrict})
+	if err != nil {
+		return nil, err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		if response.ErrorMsg != nil {
+			return nil, fmt.Errorf("%w: %s", response.ErrorCode, *response.ErrorMsg)
+		}
+		return nil, response.ErrorCode
+	}
+	var result []*ClientQuota
+	for _, entry := range response.Entries {
+		quota := &ClientQuota{Values: entry.Values}
+		for _, component := range entry.Entity {
+			name := component.Name
+			if component.MatchType == sarama.QuotaMatchDefault {
+				name = ""
+			}
+			switch component.EntityType {
+			case sarama.QuotaEntityUser:
+				quota.Entity.User = &name
+			case sarama.QuotaEntityClientID:
+				quota.Entity.ClientID = &name
+			}
+		}
+		if quota.Entity.User == nil && quota.Entity.ClientID == nil {
+			continue
+		}
+		result = append(result, quota)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Entity.String() < result[j].Entity.String()
+	})
+	return result, nil
+}
+
+// AlterClientQuotas sets the values and removes the keys.
+func AlterClientQuotas(client sarama.Client, entity QuotaEntity, values map[string]float64, removed []string, validateOnly bool) error {
+	entry := sarama.AlterClientQuotasEntry{Entity: entity.components()}
+	for key, value := range values {
+		entry.Ops = append(entry.Ops, sarama.ClientQuotasOp{Key: key, Value: value})
+	}
+	for _, key := range removed {
+		entry.Ops = append(entry.Ops, sarama.ClientQuotasOp{Key: key, Remove: true})
+	}
+	broker, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	response, err := broker.AlterClientQuotas(&sarama.AlterClientQuotasRequest{
+		Entries:      []sarama.AlterClientQuotasEntry{entry},
+		ValidateOnly: validateOnly})
+	if err != nil {
+		return err
+	}
+	for _, result := range response.Entries {
+		if result.ErrorCode != sarama.ErrNoError {
+			if result.ErrorMsg != nil {
+				return fmt.Errorf("%w: %s", result.ErrorCode, *result.ErrorMsg)
+			}
+			return result.ErrorCode
+		}
+	}
+	return nil
+}
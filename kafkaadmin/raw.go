@@ -0,0 +1,130 @@
+package kafkaadmin
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"github.com/IBM/sarama"
+	"io"
+	"net"
+	"time"
+)
+
+// rawEncoder is a minimal encoding of non-flexible request versions for APIs sarama does not implement.
+type rawEncoder struct {
+	bytes.Buffer
+}
+
+func (e *rawEncoder) putInt16(value int16) {
+	binary.Write(&e.Buffer, binary.BigEndian, value)
+}
+
+func (e *rawEncoder) putInt32(value int32) {
+	binary.Write(&e.Buffer, binary.BigEndian, value)
+}
+
+func (e *rawEncoder) putString(value string) {
+	e.putInt16(int16(len(value)))
+	e.WriteString(value)
+}
+
+type rawDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *rawDecoder) next(size int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.data) < size {
+		d.err = sarama.ErrInsufficientData
+		return nil
+	}
+	result := d.data[:size]
+	d.data = d.data[size:]
+	return result
+}
+
+func (d *rawDecoder) getInt16() int16 {
+	data := d.next(2)
+	if data == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(data))
+}
+
+func (d *rawDecoder) getInt32() int32 {
+	data := d.next(4)
+	if data == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(data))
+}
+
+func (d *rawDecoder) getString() string {
+	size := d.getInt16()
+	if size < 0 {
+		return ""
+	}
+	return string(d.next(int(size)))
+}
+
+// sendRawRequest sends the request over a separate connection and returns the response body.
+// TLS settings of the client are honoured, SASL authentication is not implemented.
+func sendRawRequest(client sarama.Client, broker *sarama.Broker, key int16, version int16, body []byte) ([]byte, error) {
+	config := client.Config()
+	if config.Net.SASL.Enable {
+		return nil, fmt.Errorf("%s is not supported with SASL authentication", ApiKeyNames[key])
+	}
+	dialer := &net.Dialer{Timeout: config.Net.DialTimeout, KeepAlive: config.Net.KeepAlive, LocalAddr: config.Net.LocalAddr}
+	var connection net.Conn
+	var err error
+	if config.Net.TLS.Enable {
+		tlsConfig := config.Net.TLS.Config
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		connection, err = tls.DialWithDialer(dialer, "tcp", broker.Addr(), tlsConfig)
+	} else {
+		connection, err = dialer.Dial("tcp", broker.Addr())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+	connection.SetDeadline(time.Now().Add(config.Net.ReadTimeout))
+	const correlationID = 1
+	var request rawEncoder
+	request.putInt16(key)
+	request.putInt16(version)
+	request.putInt32(correlationID)
+	request.putString(config.ClientID)
+	request.Write(body)
+	var frame rawEncoder
+	frame.putInt32(int32(request.Len()))
+	frame.Write(request.Bytes())
+	_, err = connection.Write(frame.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 8)
+	_, err = io.ReadFull(connection, header)
+	if err != nil {
+		return nil, err
+	}
+	if int32(binary.BigEndian.Uint32(header[4:])) != correlationID {
+		return nil, fmt.Errorf("unexpected correlation id in the response of %s", broker.Addr())
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size < 4 {
+		return nil, fmt.Errorf("invalid response size %d from %s", size, broker.Addr())
+	}
+	response := make([]byte, size-4)
+	_, err = io.ReadFull(connection, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,168 @@
+package kafkaadmin
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Assignment = map[string]map[int32][]int32
+
+type Reassignments = map[string]map[int32]*sarama.PartitionReplicaReassignmentsStatus
+
+// ListReassignments lists every ongoing reassignment.
+func ListReassignments(client sarama.Client) (Reassignments, error) {
+	controller, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	response, err := controller.ListPartitionReassignments(&sarama.ListPartitionReassignmentsRequest{TimeoutMs: 60000})
+	if err != nil {
+		return nil, err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		return nil, response.ErrorCode
+	}
+	if response.TopicStatus == nil {
+		return make(Reassignments), nil
+	}
+	return response.TopicStatus, nil
+}
+
+// ReassignmentTarget returns the replicas a partition will have when its reassignment completes.
+func ReassignmentTarget(status *sarama.PartitionReplicaReassignmentsStatus) []int32 {
+	var result []int32
+	for _, replica := range status.Replicas {
+		removing := false
+		for _, removed := range status.RemovingReplicas {
+			if removed == replica {
+				removing = true
+				break
+			}
+		}
+		if !removing {
+			result = append(result, replica)
+		}
+	}
+	return result
+}
+
+// GetAssignment returns current replicas of every partition of the topics (all topics if none are given).
+func GetAssignment(client sarama.Client, topics ...string) (Assignment, error) {
+	metadata, err := GetMetadata(client, topics...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Assignment)
+	for _, topic := range metadata.Topics {
+		if topic.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("%s: %w", topic.Name, topic.Err)
+		}
+		result[topic.Name] = make(map[int32][]int32)
+		for _, partition := range topic.Partitions {
+			result[topic.Name][partition.ID] = partition.Replicas
+		}
+	}
+	return result, nil
+}
+
+// ClusterAssignment returns replicas of every topic in the cluster, topics with metadata errors are skipped
+func ClusterAssignment(client sarama.Client) (Assignment, error) {
+	metadata, err := GetMetadata(client)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Assignment)
+	for _, topic := range metadata.Topics {
+		if topic.Err != sarama.ErrNoError {
+			continue
+		}
+		result[topic.Name] = make(map[int32][]int32)
+		for _, partition := range topic.Partitions {
+			result[topic.Name][partition.ID] = partition.Replicas
+		}
+	}
+	return result, nil
+}
+
+// AlterReassignments starts reassignments of the given partitions only, a nil replica list cancels the ongoing
+// reassignment of the partition.
+func AlterReassignments(client sarama.Client, assignment Assignment) error {
+	var topics []string
+	for topic := range assignment {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	current, err := GetAssignment(client, topics...)
+	if err != nil {
+		return err
+	}
+	request := &sarama.AlterPartitionReassignmentsRequest{TimeoutMs: int32(client.Config().Admin.Timeout / time.Millisecond)}
+	for _, topic := range topics {
+		for partition, replicas := range assignment[topic] {
+			if _, ok := current[topic][partition]; !ok {
+				return fmt.Errorf("topic %s has no partition %d", topic, partition)
+			}
+			request.AddBlock(topic, partition, replicas)
+		}
+	}
+	controller, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	response, err := controller.AlterPartitionReassignments(request)
+	if err != nil {
+		return err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		if response.ErrorMessage != nil {
+			return fmt.Errorf("%w: %s", response.ErrorCode, *response.ErrorMessage)
+		}
+		return response.ErrorCode
+	}
+	var messages []string
+	for topic, partitions := range response.Errors {
+		for partition, block := range partitions {
+			code, message := partitionReassignmentError(block)
+			if code == sarama.ErrNoError {
+				continue
+			}
+			text := fmt.Sprintf("%s/%d: %v", topic, partition, code)
+			if len(message) > 0 {
+				text += ": " + message
+			}
+			messages = append(messages, text)
+		}
+	}
+	if len(messages) > 0 {
+		sort.Strings(messages)
+		return fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// partitionReassignmentError reads the error of a partition from the response block. sarama (v1.45.2 in go.mod)
+// keeps the block fields unexported and has no accessor, TestPartitionReassignmentError checks they are still
+// there after an upgrade. A block that cannot be read is reported as an unknown error rather than ignored.
+func partitionReassignmentError(block interface{}) (sarama.KError, string) {
+	value := reflect.ValueOf(block)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return sarama.ErrNoError, ""
+	}
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return sarama.ErrUnknown, "unexpected partition error block"
+	}
+	code := value.FieldByName("errorCode")
+	message := value.FieldByName("errorMessage")
+	if code.Kind() != reflect.Int16 || message.Kind() != reflect.Ptr || message.Type().Elem().Kind() != reflect.String {
+		return sarama.ErrUnknown, "unexpected partition error block"
+	}
+	if message.IsNil() {
+		return sarama.KError(code.Int()), ""
+	}
+	return sarama.KError(code.Int()), message.Elem().String()
+}
@@ -0,0 +1,34 @@
+package kafkaadmin
+
+import (
+	"github.com/IBM/sarama"
+	"testing"
+)
+
+func TestPartitionReassignmentError(t *testing.T) {
+	message := "replica 4 is not alive"
+	response := &sarama.AlterPartitionReassignmentsResponse{}
+	response.AddError("test", 0, sarama.ErrNoError, nil)
+	response.AddError("test", 1, sarama.ErrReplicaNotAvailable, &message)
+	response.AddError("test", 2, sarama.ErrNoReassignmentInProgress, nil)
+	for _, test := range []struct {
+		partition int32
+		code      sarama.KError
+		message   string
+	}{
+		{partition: 0, code: sarama.ErrNoError},
+		{partition: 1, code: sarama.ErrReplicaNotAvailable, message: message},
+		{partition: 2, code: sarama.ErrNoReassignmentInProgress},
+	} {
+		code, result := partitionReassignmentError(response.Errors["test"][test.partition])
+		if code != test.code || result != test.message {
+			t.Errorf("%d: got %v %q, expected %v %q", test.partition, code, result, test.code, test.message)
+		}
+	}
+	if code, _ := partitionReassignmentError(nil); code != sarama.ErrNoError {
+		t.Errorf("nil block: got %v, expected no error", code)
+	}
+	if code, _ := partitionReassignmentError(&struct{ errorCode string }{}); code != sarama.ErrUnknown {
+		t.Errorf("unexpected block: got %v, expected %v", code, sarama.ErrUnknown)
+	}
+}
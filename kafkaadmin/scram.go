@@ -0,0 +1,117 @@
+package kafkaadmin
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/IBM/sarama"
+	"sort"
+	"strings"
+)
+
+const (
+	DescribeUserScramCredentialsKey = 50
+	AlterUserScramCredentialsKey    = 51
+)
+
+// errResourceNotFound is RESOURCE_NOT_FOUND, returned for users without credentials, sarama does not define it.
+const errResourceNotFound sarama.KError = 91
+
+type ScramCredential struct {
+	Mechanism  sarama.ScramMechanismType
+	Iterations int32
+}
+
+type ScramUser struct {
+	Name        string
+	Credentials []ScramCredential
+}
+
+func ParseScramMechanism(name string) (sarama.ScramMechanismType, error) {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SCRAM-") {
+	case "SHA-256", "SHA256":
+		return sarama.SCRAM_MECHANISM_SHA_256, nil
+	case "SHA-512", "SHA512":
+		return sarama.SCRAM_MECHANISM_SHA_512, nil
+	}
+	return sarama.SCRAM_MECHANISM_UNKNOWN, fmt.Errorf("unknown SCRAM mechanism %s, expected SCRAM-SHA-256 or SCRAM-SHA-512", name)
+}
+
+// DescribeScramUsers returns all users with credentials if none are given, unknown users are skipped.
+func DescribeScramUsers(client sarama.Client, users ...string) ([]*ScramUser, error) {
+	request := &sarama.DescribeUserScramCredentialsRequest{}
+	for _, user := range users {
+		request.DescribeUsers = append(request.DescribeUsers, sarama.DescribeUserScramCredentialsRequestUser{Name: user})
+	}
+	broker, err := client.Controller()
+	if err != nil {
+		return nil, err
+	}
+	response, err := broker.DescribeUserScramCredentials(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.ErrorCode != sarama.ErrNoError {
+		if response.ErrorMessage != nil {
+			return nil, fmt.Errorf("%w: %s", response.ErrorCode, *response.ErrorMessage)
+		}
+		return nil, response.ErrorCode
+	}
+	var result []*ScramUser
+	for _, item := range response.Results {
+		if item.ErrorCode == errResourceNotFound {
+			continue
+		}
+		if item.ErrorCode != sarama.ErrNoError {
+			return nil, fmt.Errorf("user %s: %w", item.User, item.ErrorCode)
+		}
+		user := &ScramUser{Name: item.User}
+		for _, info := range item.CredentialInfos {
+			user.Credentials = append(user.Credentials, ScramCredential{Mechanism: info.Mechanism, Iterations: info.Iterations})
+		}
+		sort.Slice(user.Credentials, func(i, j int) bool {
+			return user.Credentials[i].Mechanism < user.Credentials[j].Mechanism
+		})
+		result = append(result, user)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// AlterScramCredentials generates a random salt for upsertions without one, the password itself is never sent.
+func AlterScramCredentials(client sarama.Client, upsertions []sarama.AlterUserScramCredentialsUpsert, deletions []sarama.AlterUserScramCredentialsDelete) error {
+	for i := range upsertions {
+		if len(upsertions[i].Salt) > 0 {
+			continue
+		}
+		upsertions[i].Salt = make([]byte, 32)
+		_, err := rand.Read(upsertions[i].Salt)
+		if err != nil {
+			return err
+		}
+	}
+	broker, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	response, err := broker.AlterUserScramCredentials(&sarama.AlterUserScramCredentialsRequest{Upsertions: upsertions, Deletions: deletions})
+	if err != nil {
+		return err
+	}
+	var messages []string
+	for _, result := range response.Results {
+		if result.ErrorCode == sarama.ErrNoError {
+			continue
+		}
+		message := fmt.Sprintf("user %s: %v", result.User, result.ErrorCode)
+		if result.ErrorMessage != nil {
+			message += ": " + *result.ErrorMessage
+		}
+		messages = append(messages, message)
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package kafkaadmin
 
 import (
-	"github.com/Shopify/sarama"
+	"github.com/IBM/sarama"
 	"strconv"
 	"time"
 )
@@ -21,6 +21,36 @@ func (t TopicErrors) Error() string {
 	return result
 }
 
+type PartitionErrors map[string]map[int32]sarama.KError
+
+func (p PartitionErrors) Error() string {
+	result := ""
+	for topic, partitions := range p {
+		for partition, value := range partitions {
+			if len(result) != 0 {
+				result += "\n"
+			}
+			result += "Error at " + topic + "/" + strconv.Itoa(int(partition)) + ":" + value.Error() + " (" + strconv.Itoa(int(value)) + ")"
+		}
+	}
+	return result
+}
+
+func (p PartitionErrors) add(topic string, partition int32, err sarama.KError) {
+	if p[topic] == nil {
+		p[topic] = make(map[int32]sarama.KError)
+	}
+	p[topic][partition] = err
+}
+
+func (p PartitionErrors) merge(other PartitionErrors) {
+	for topic, partitions := range other {
+		for partition, err := range partitions {
+			p.add(topic, partition, err)
+		}
+	}
+}
+
 func DeleteTopics(client sarama.Client, topics ...string) error {
 	broker, err := client.Controller()
 	if err != nil {
@@ -45,28 +75,58 @@ func DeleteTopics(client sarama.Client, topics ...string) error {
 	return nil
 }
 
-func CreateTopics(client sarama.Client, partitions int32, replicationFactor int16, configs map[string]string, topics ...string) error {
-	broker, err := client.Controller()
-	if err != nil {
-		return err
+func topicConfigEntries(configs map[string]string) map[string]*string {
+	result := make(map[string]*string)
+	for name, value := range configs {
+		pvalue := new(string)
+		*pvalue = value
+		result[name] = pvalue
 	}
-	var request sarama.CreateTopicsRequest
-	request.Version = 4
-	request.Timeout = DefaultDuration
-	request.TopicDetails = make(map[string]*sarama.TopicDetail)
+	return result
+}
+
+func CreateTopics(client sarama.Client, partitions int32, replicationFactor int16, configs map[string]string, validateOnly bool, topics ...string) error {
+	details := make(map[string]*sarama.TopicDetail)
 	for _, topic := range topics {
 		detail := &sarama.TopicDetail{
 			ReplicationFactor: replicationFactor,
 			NumPartitions:     partitions}
-		detail.ConfigEntries = make(map[string]*string)
-		for name, value := range configs {
-			pvalue := new(string)
-			*pvalue = value
-			detail.ConfigEntries[name] = pvalue
-		}
+		detail.ConfigEntries = topicConfigEntries(configs)
 		detail.ReplicaAssignment = make(map[int32][]int32)
-		request.TopicDetails[topic] = detail
+		details[topic] = detail
 	}
+	return CreateTopicDetails(client, details, validateOnly)
+}
+
+// CreateAssignedTopics creates topics with explicit replicas of every partition.
+func CreateAssignedTopics(client sarama.Client, assignments map[string][][]int32, configs map[string]string, validateOnly bool) error {
+	details := make(map[string]*sarama.TopicDetail)
+	for topic, replicas := range assignments {
+		detail := &sarama.TopicDetail{
+			ReplicationFactor: -1,
+			NumPartitions:     -1,
+			ConfigEntries:     topicConfigEntries(configs),
+			ReplicaAssignment: make(map[int32][]int32)}
+		for partition, partitionReplicas := range replicas {
+			detail.ReplicaAssignment[int32(partition)] = partitionReplicas
+		}
+		details[topic] = detail
+	}
+	return CreateTopicDetails(client, details, validateOnly)
+}
+
+// CreateTopicDetails creates topics from details, the replication factor and the number of partitions have to
+// be -1 in details with replica assignment.
+func CreateTopicDetails(client sarama.Client, details map[string]*sarama.TopicDetail, validateOnly bool) error {
+	broker, err := client.Controller()
+	if err != nil {
+		return err
+	}
+	var request sarama.CreateTopicsRequest
+	request.Version = 4
+	request.Timeout = DefaultDuration
+	request.ValidateOnly = validateOnly
+	request.TopicDetails = details
 	response, err := broker.CreateTopics(&request)
 	if err != nil {
 		return err
@@ -83,17 +143,23 @@ func CreateTopics(client sarama.Client, partitions int32, replicationFactor int1
 	return nil
 }
 
-func CreatePartitions(client sarama.Client, partitions int32, topics ...string) error {
+func CreatePartitions(client sarama.Client, partitions int32, validateOnly bool, topics ...string) error {
+	topicPartitions := make(map[string]*sarama.TopicPartition)
+	for _, topic := range topics {
+		topicPartitions[topic] = &sarama.TopicPartition{Count: partitions}
+	}
+	return CreateTopicPartitions(client, topicPartitions, validateOnly)
+}
+
+func CreateTopicPartitions(client sarama.Client, topicPartitions map[string]*sarama.TopicPartition, validateOnly bool) error {
 	broker, err := client.Controller()
 	if err != nil {
 		return err
 	}
 	var request sarama.CreatePartitionsRequest
 	request.Timeout = DefaultDuration
-	request.TopicPartitions = make(map[string]*sarama.TopicPartition)
-	for _, topic := range topics {
-		request.TopicPartitions[topic] = &sarama.TopicPartition{Count: partitions}
-	}
+	request.ValidateOnly = validateOnly
+	request.TopicPartitions = topicPartitions
 	response, err := broker.CreatePartitions(&request)
 	if err != nil {
 		return err
@@ -110,36 +176,6 @@ func CreatePartitions(client sarama.Client, partitions int32, topics ...string)
 	return nil
 }
 
-func ModifyTopicConfig(client sarama.Client, config map[string]string, topics ...string) error {
-	broker, err := client.Controller()
-	if err != nil {
-		return err
-	}
-	var request sarama.AlterConfigsRequest
-	for _, topic := range topics {
-		resource := &sarama.AlterConfigsResource{
-			Type: sarama.TopicResource,
-			Name: topic}
-		resource.ConfigEntries = make(map[string]*string)
-		for name, value := range config {
-			pvalue := new(string)
-			*pvalue = value
-			resource.ConfigEntries[name] = pvalue
-		}
-		request.Resources = append(request.Resources, resource)
-	}
-	response, err := broker.AlterConfigs(&request)
-	if err != nil {
-		return err
-	}
-	errors := make(TopicErrors)
-	for _, resource := range response.Resources {
-		if resource != nil && sarama.KError(resource.ErrorCode) != sarama.ErrNoError {
-			errors[resource.Name] = sarama.KError(resource.ErrorCode)
-		}
-	}
-	if len(errors) > 0 {
-		return errors
-	}
-	return nil
+func ModifyTopicConfig(client sarama.Client, changes ConfigChanges, validateOnly bool, topics ...string) error {
+	return AlterConfigs(client, sarama.TopicResource, changes, validateOnly, topics...)
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type leaderElectCmdType struct {
+	selector   topicSelector
+	partitions []int
+	isUnclean  bool
+	guard      destructiveGuard
+}
+
+func (l *leaderElectCmdType) Run(cmd *cobra.Command, args []string) error {
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	topics, metadata, err := l.selector.Select(client, args)
+	if err != nil {
+		return err
+	}
+	if len(topics) == 0 {
+		fmt.Println("no topics selected")
+		return nil
+	}
+	if len(l.partitions) > 0 && len(topics) != 1 {
+		return fmt.Errorf("--partitions requires exactly one topic")
+	}
+	wanted := intsToInt32(l.partitions)
+	partitions := make(kafkaadmin.TopicPartitions)
+	count := 0
+	for _, topic := range topics {
+		for _, partition := range metadata[topic].Partitions {
+			if len(wanted) == 0 || inInt32Array(partition.ID, wanted) {
+				partitions[topic] = append(partitions[topic], partition.ID)
+				count++
+			}
+		}
+	}
+	if len(l.partitions) > 0 && count != len(l.partitions) {
+		return fmt.Errorf("topic %s does not have all of the partitions %v", topics[0], l.partitions)
+	}
+	electionType := sarama.PreferredElection
+	if l.isUnclean {
+		electionType = sarama.UncleanElection
+		err = l.guard.check(client, "elected uncleanly (messages may be lost)", topics, false)
+		if err != nil {
+			return err
+		}
+	}
+	electAudit := newAudit(client)
+	describeElections(electAudit, electionType, partitions)
+	err = electAudit.finish(kafkaadmin.ElectLeaders(client, electionType, partitions))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("election done for %d partitions of %d topics\n", count, len(topics))
+	return nil
+}
+
+func describeElections(electAudit *audit, electionType sarama.ElectionType, partitions kafkaadmin.TopicPartitions) {
+	kind := "preferred"
+	if electionType == sarama.UncleanElection {
+		kind = "unclean"
+	}
+	var topics []string
+	for topic := range partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		electAudit.describe(auditElection, topic, "%s election of partitions %v", kind, partitions[topic])
+	}
+}
+
+type leaderBalanceCmdType struct {
+	shouldExecute bool
+}
+
+func printLeaderStats(stats map[int32]*kafkaadmin.LeaderStats) error {
+	var brokers []int32
+	for broker := range stats {
+		brokers = append(brokers, broker)
+	}
+	sort.Slice(brokers, func(i, j int) bool {
+		return brokers[i] < brokers[j]
+	})
+	var rows [][]string
+	for _, broker := range brokers {
+		item := stats[broker]
+		rows = append(rows, []string{
+			strconv.Itoa(int(broker)),
+			strconv.Itoa(item.Leaders),
+			strconv.Itoa(item.Preferred),
+			fmt.Sprintf("%+d", item.Leaders-item.Preferred),
+			strconv.Itoa(item.Replicas)})
+	}
+	return writeTable(os.Stdout, []string{"broker", "leaders", "preferred", "skew", "replicas"}, rows)
+}
+
+func misplacedLeaders(metadata *sarama.MetadataResponse) kafkaadmin.TopicPartitions {
+	result := make(kafkaadmin.TopicPartitions)
+	for _, topic := range metadata.Topics {
+		for _, partition := range topic.Partitions {
+			if len(partition.Replicas) > 0 && partition.Leader != partition.Replicas[0] {
+				result[topic.Name] = append(result[topic.Name], partition.ID)
+			}
+		}
+	}
+	return result
+}
+
+func (l *leaderBalanceCmdType) Run(cmd *cobra.Command, args []string) error {
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	metadata, err := kafkaadmin.GetMetadata(client)
+	if err != nil {
+		return err
+	}
+	err = printLeaderStats(kafkaadmin.GetLeaderStats(client, metadata))
+	if err != nil {
+		return err
+	}
+	misplaced := misplacedLeaders(metadata)
+	count := 0
+	for _, partitions := range misplaced {
+		count += len(partitions)
+	}
+	fmt.Printf("%d partitions are not led by their preferred leader\n", count)
+	if !l.shouldExecute || count == 0 {
+		return nil
+	}
+	balanceAudit := newAudit(client)
+	describeElections(balanceAudit, sarama.PreferredElection, misplaced)
+	err = balanceAudit.finish(kafkaadmin.ElectLeaders(client, sarama.PreferredElection, misplaced))
+	if err != nil {
+		fmt.Println("Errors while electing preferred leaders")
+		fmt.Println(err)
+	}
+	metadata, err = kafkaadmin.GetMetadata(client)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nafter preferred leader election:")
+	return printLeaderStats(kafkaadmin.GetLeaderStats(client, metadata))
+}
+
+var leaderElectCmd = &cobra.Command{
+	Use:   "elect [topics or glob patterns]",
+	Short: "elect preferred leaders (or any replica with --unclean), all partitions by default"}
+
+var leaderBalanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "show leader skew per broker, --execute moves leadership to preferred leaders"}
+
+func init() {
+	var electRunner leaderElectCmdType
+	leaderElectCmd.RunE = electRunner.Run
+	flags := leaderElectCmd.Flags()
+	electRunner.selector.addFlags(flags)
+	flags.IntSliceVarP(&electRunner.partitions, "partitions", "p", nil, "partitions of the topic (all by default)")
+	flags.BoolVar(&electRunner.isUnclean, "unclean", false, "unclean election, out-of-sync replicas may become leaders")
+	electRunner.guard.addFlags(flags)
+	var balanceRunner leaderBalanceCmdType
+	leaderBalanceCmd.RunE = balanceRunner.Run
+	leaderBalanceCmd.Flags().BoolVar(&balanceRunner.shouldExecute, "execute", false, "elect preferred leaders for partitions led by other brokers")
+}
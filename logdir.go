@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
+)
+
+func parseInt32(value string, name string) (int32, error) {
+	result, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return int32(result), nil
+}
+
+func printReplicaMoves(moves []kafkaadmin.ReplicaMove, sources map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir) {
+	for _, move := range moves {
+		source := sources[move]
+		fmt.Printf("broker %d %s/%d: %s -> %s (%s)\n", move.Broker, move.Topic, move.Partition, source.Path, move.Path, formatBinarySize(source.Size))
+	}
+}
+
+func auditReplicaMoves(client sarama.Client, moves []kafkaadmin.ReplicaMove, sources map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir) error {
+	moveAudit := newAudit(client)
+	for _, move := range moves {
+		moveAudit.describe(auditLogDir, fmt.Sprintf("%s/%d", move.Topic, move.Partition), "broker %d: %s -> %s", move.Broker, sources[move].Path, move.Path)
+	}
+	return moveAudit.finish(kafkaadmin.AlterReplicaLogDirs(client, moves))
+}
+
+type logdirMoveCmdType struct{}
+
+func (l *logdirMoveCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("broker, topic, partition and log dir expected")
+	}
+	broker, err := parseInt32(args[0], "broker id")
+	if err != nil {
+		return err
+	}
+	partition, err := parseInt32(args[2], "partition")
+	if err != nil {
+		return err
+	}
+	move := kafkaadmin.ReplicaMove{Broker: broker, Topic: args[1], Partition: partition, Path: args[3]}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	logDirs, err := kafkaadmin.DescribeLogDirs(client, move.Topic)
+	if err != nil {
+		return err
+	}
+	var source *kafkaadmin.ReplicaDir
+	pathFound := false
+	for _, dir := range logDirs {
+		if dir.Broker != broker {
+			continue
+		}
+		if dir.Path == move.Path {
+			pathFound = true
+		}
+		for _, replica := range dir.Replicas {
+			if replica.Topic == move.Topic && replica.Partition == partition && !replica.IsFuture {
+				source = replica
+			}
+		}
+	}
+	if !pathFound {
+		return fmt.Errorf("broker %d has no log dir %s", broker, move.Path)
+	}
+	if source == nil {
+		return fmt.Errorf("broker %d has no replica of %s/%d", broker, move.Topic, partition)
+	}
+	if source.Path == move.Path {
+		fmt.Println("the replica is already in", move.Path)
+		return nil
+	}
+	printReplicaMoves([]kafkaadmin.ReplicaMove{move}, map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir{move: source})
+	err = auditReplicaMoves(client, []kafkaadmin.ReplicaMove{move}, map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir{move: source})
+	if err != nil {
+		return err
+	}
+	fmt.Println("move started, see topic usage --replicas for progress")
+	return nil
+}
+
+type logdirBalanceCmdType struct {
+	brokers       []int
+	threshold     float64
+	shouldExecute bool
+}
+
+type diskUsage struct {
+	path     string
+	size     int64
+	replicas []*kafkaadmin.ReplicaDir
+}
+
+// planBroker greedily moves the largest replica that narrows the gap between the fullest and the emptiest dir
+// of the broker, until the gap is below the threshold (percent of the average dir usage).
+func (l *logdirBalanceCmdType) planBroker(dirs []*diskUsage, sources map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir) []kafkaadmin.ReplicaMove {
+	var total int64
+	for _, dir := range dirs {
+		total += dir.size
+	}
+	allowed := int64(float64(total) / float64(len(dirs)) * l.threshold / 100)
+	var result []kafkaadmin.ReplicaMove
+	moved := make(map[*kafkaadmin.ReplicaDir]bool)
+	for {
+		sort.Slice(dirs, func(i, j int) bool {
+			return dirs[i].size > dirs[j].size
+		})
+		fullest, emptiest := dirs[0], dirs[len(dirs)-1]
+		gap := fullest.size - emptiest.size
+		if gap <= allowed {
+			return result
+		}
+		sort.Slice(fullest.replicas, func(i, j int) bool {
+			return fullest.replicas[i].Size > fullest.replicas[j].Size
+		})
+		index := -1
+		for i, replica := range fullest.replicas {
+			if !moved[replica] && replica.Size > 0 && replica.Size < gap {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return result
+		}
+		replica := fullest.replicas[index]
+		fullest.replicas = append(fullest.replicas[:index], fullest.replicas[index+1:]...)
+		fullest.size -= replica.Size
+		emptiest.replicas = append(emptiest.replicas, replica)
+		emptiest.size += replica.Size
+		moved[replica] = true
+		move := kafkaadmin.ReplicaMove{Broker: replica.Broker, Topic: replica.Topic, Partition: replica.Partition, Path: emptiest.path}
+		sources[move] = replica
+		result = append(result, move)
+	}
+}
+
+func (l *logdirBalanceCmdType) Run(cmd *cobra.Command, args []string) error {
+	if l.threshold < 0 {
+		return fmt.Errorf("threshold has to be positive")
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	logDirs, err := kafkaadmin.DescribeLogDirs(client)
+	if err != nil {
+		return err
+	}
+	byBroker := make(map[int32][]*diskUsage)
+	sources := make(map[kafkaadmin.ReplicaMove]*kafkaadmin.ReplicaDir)
+	moving := make(map[int32]bool)
+	for _, dir := range logDirs {
+		if len(l.brokers) > 0 && !inInt32Array(dir.Broker, intsToInt32(l.brokers)) {
+			continue
+		}
+		if dir.Err != 0 {
+			fmt.Printf("broker %d %s skipped: %v\n", dir.Broker, dir.Path, dir.Err)
+			continue
+		}
+		usage := &diskUsage{path: dir.Path}
+		for _, replica := range dir.Replicas {
+			if replica.IsFuture {
+				moving[dir.Broker] = true
+				continue
+			}
+			usage.size += replica.Size
+			usage.replicas = append(usage.replicas, replica)
+		}
+		byBroker[dir.Broker] = append(byBroker[dir.Broker], usage)
+	}
+	var brokers []int32
+	for broker := range byBroker {
+		brokers = append(brokers, broker)
+	}
+	sort.Slice(brokers, func(i, j int) bool {
+		return brokers[i] < brokers[j]
+	})
+	var moves []kafkaadmin.ReplicaMove
+	var rows [][]string
+	for _, broker := range brokers {
+		dirs := byBroker[broker]
+		before := make(map[string]int64)
+		for _, dir := range dirs {
+			before[dir.path] = dir.size
+		}
+		if len(dirs) < 2 {
+			continue
+		}
+		if moving[broker] {
+			fmt.Printf("broker %d skipped: replicas are being moved between its log dirs\n", broker)
+			continue
+		}
+		moves = append(moves, l.planBroker(dirs, sources)...)
+		sort.Slice(dirs, func(i, j int) bool {
+			return dirs[i].path < dirs[j].path
+		})
+		for _, dir := range dirs {
+			rows = append(rows, []string{strconv.Itoa(int(broker)), dir.path, formatBinarySize(before[dir.path]), formatBinarySize(dir.size)})
+		}
+	}
+	if len(rows) > 0 {
+		err = writeTable(os.Stdout, []string{"broker", "dir", "before", "after"}, rows)
+		if err != nil {
+			return err
+		}
+		fmt.Println("")
+	}
+	if len(moves) == 0 {
+		fmt.Println("log dirs are balanced")
+		return nil
+	}
+	printReplicaMoves(moves, sources)
+	if !l.shouldExecute {
+		return nil
+	}
+	err = auditReplicaMoves(client, moves, sources)
+	if err != nil {
+		return err
+	}
+	fmt.Println("moves started, see topic usage --replicas for progress")
+	return nil
+}
+
+func intsToInt32(values []int) []int32 {
+	var result []int32
+	for _, value := range values {
+		result = append(result, int32(value))
+	}
+	return result
+}
+
+var logdirMoveCmd = &cobra.Command{
+	Use:   "move <broker> <topic> <partition> <log dir>",
+	Short: "move a partition replica to another log dir of the same broker"}
+
+var logdirBalanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "propose (or with --execute start) replica moves between log dirs to even out disk usage of every broker"}
+
+func init() {
+	var moveRunner logdirMoveCmdType
+	logdirMoveCmd.RunE = moveRunner.Run
+	var balanceRunner logdirBalanceCmdType
+	logdirBalanceCmd.RunE = balanceRunner.Run
+	flags := logdirBalanceCmd.Flags()
+	flags.IntSliceVar(&balanceRunner.brokers, "brokers", nil, "brokers to balance (all by default)")
+	flags.Float64Var(&balanceRunner.threshold, "threshold", 10, "allowed difference between log dirs in percent of the average usage")
+	flags.BoolVar(&balanceRunner.shouldExecute, "execute", false, "start the moves")
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cobra"
 	//	"github.com/spf13/pflag"
 	"github.com/tvanomr/kafkatool/flagtypes"
+	"os"
 )
 
 var hostPort = flagtypes.HostPort{Host: "localhost", Port: 9092}
@@ -11,10 +12,72 @@ var hostPort = flagtypes.HostPort{Host: "localhost", Port: 9092}
 func main() {
 	rootCmd := &cobra.Command{Use: "cmd"}
 	rootCmd.PersistentFlags().VarP(&hostPort, "address", "a", "kafka server address")
+	rootCmd.PersistentFlags().StringVar(&configFileName, "config-file", "", "config file (default "+defaultConfigFileName()+")")
 	topicCmd := &cobra.Command{Use: "topic", Aliases: []string{"t"}}
+	topicCmd.PersistentFlags().StringVar(&presetFileName, "preset-file", "", "YAML file with additional topic config presets")
 	rootCmd.AddCommand(topicCmd)
 	topicCmd.AddCommand(topicListCmd)
 	topicCmd.AddCommand(topicModCmd)
+	topicCmd.AddCommand(topicDescribeCmd)
+	topicCmd.AddCommand(topicUsageCmd)
+	topicCmd.AddCommand(topicTruncateCmd)
+	topicCmd.AddCommand(topicExportCmd)
+	topicCmd.AddCommand(topicPresetsCmd)
+	partitionCmd := &cobra.Command{Use: "partition", Aliases: []string{"p"}}
+	rootCmd.AddCommand(partitionCmd)
+	partitionCmd.AddCommand(partitionReassignCmd)
+	partitionCmd.AddCommand(partitionStatusCmd)
+	partitionCmd.AddCommand(partitionCancelCmd)
+	leaderCmd := &cobra.Command{Use: "leader"}
+	rootCmd.AddCommand(leaderCmd)
+	leaderCmd.AddCommand(leaderElectCmd)
+	leaderCmd.AddCommand(leaderBalanceCmd)
+	logdirCmd := &cobra.Command{Use: "logdir"}
+	rootCmd.AddCommand(logdirCmd)
+	logdirCmd.AddCommand(logdirMoveCmd)
+	logdirCmd.AddCommand(logdirBalanceCmd)
+	aclCmd := &cobra.Command{Use: "acl"}
+	rootCmd.AddCommand(aclCmd)
+	aclCmd.AddCommand(aclListCmd)
+	aclCmd.AddCommand(aclAddCmd)
+	aclCmd.AddCommand(aclRemoveCmd)
+	aclCmd.AddCommand(aclApplyCmd)
+	quotaCmd := &cobra.Command{Use: "quota"}
+	rootCmd.AddCommand(quotaCmd)
+	quotaCmd.AddCommand(quotaDescribeCmd)
+	quotaCmd.AddCommand(quotaSetCmd)
+	quotaCmd.AddCommand(quotaDeleteCmd)
+	userCmd := &cobra.Command{Use: "user"}
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userDescribeCmd)
+	userCmd.AddCommand(userCreateCmd)
+	userCmd.AddCommand(userRotateCmd)
+	userCmd.AddCommand(userDeleteCmd)
+	benchCmd := &cobra.Command{Use: "bench"}
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.AddCommand(benchProduceCmd)
+	benchCmd.AddCommand(benchConsumeCmd)
+	groupCmd := &cobra.Command{Use: "group", Aliases: []string{"g"}}
+	rootCmd.AddCommand(groupCmd)
+	groupCmd.AddCommand(groupExportCmd)
+	groupCmd.AddCommand(groupImportCmd)
+	clusterCmd := &cobra.Command{Use: "cluster", Aliases: []string{"c"}}
+	rootCmd.AddCommand(clusterCmd)
+	clusterCmd.AddCommand(clusterDescribeCmd)
+	brokerCmd := &cobra.Command{Use: "broker", Aliases: []string{"b"}}
+	rootCmd.AddCommand(brokerCmd)
+	brokerConfigCmd := &cobra.Command{Use: "config", Aliases: []string{"c"}}
+	brokerCmd.AddCommand(brokerConfigCmd)
+	brokerConfigCmd.AddCommand(brokerConfigGetCmd)
+	brokerConfigCmd.AddCommand(brokerConfigSetCmd)
+	brokerConfigCmd.AddCommand(brokerConfigUnsetCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(readCmd)
-	rootCmd.Execute()
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(undoCmd)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the command line instead of the tests when the exit code of a command is checked.
+func TestMain(m *testing.M) {
+	if os.Getenv("KAFKATOOL_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runMain(t *testing.T, args ...string) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "KAFKATOOL_RUN_MAIN=1", "HOME="+t.TempDir(), "XDG_CONFIG_HOME="+t.TempDir())
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestPlanExitCodes(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "topics.yaml")
+	err := os.WriteFile(spec, []byte("topics:\n  - name: test\n    partitions: 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{name: "help", args: []string{"plan", "--help"}, expected: 0},
+		{name: "missing spec", args: []string{"plan", "-f", filepath.Join(t.TempDir(), "missing.yaml")}, expected: 1},
+		{name: "unreachable cluster", args: []string{"plan", "-f", spec, "-a", "127.0.0.1:1"}, expected: 1},
+		{name: "apply with unreachable cluster", args: []string{"apply", "-f", spec, "-a", "127.0.0.1:1"}, expected: 1},
+		{name: "unknown flag", args: []string{"plan", "--unknown"}, expected: 1},
+	} {
+		if code := runMain(t, test.args...); code != test.expected {
+			t.Errorf("%s: exit code %d, expected %d", test.name, code, test.expected)
+		}
+	}
+}
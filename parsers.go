@@ -33,6 +33,14 @@ func parseBinarySize(value string) (int64, error) {
 	return intValue * factor, err
 }
 
+func parseKeyValue(pair string) (string, string, error) {
+	index := strings.Index(pair, "=")
+	if index <= 0 {
+		return "", "", fmt.Errorf("invalid key=value pair %s", pair)
+	}
+	return pair[:index], pair[index+1:], nil
+}
+
 func parseDuration(value string) (time.Duration, error) {
 	trimmed := strings.TrimSpace(value)
 	var result time.Duration
@@ -96,3 +104,54 @@ func parseDuration(value string) (time.Duration, error) {
 	}
 	return time.Duration(0), fmt.Errorf("unexpected suffix, %s", trimmed)
 }
+
+func formatBinarySize(value int64) string {
+	units := []string{"", "K", "M", "G", "T"}
+	size := float64(value)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return strconv.FormatInt(value, 10)
+	}
+	return strconv.FormatFloat(size, 'f', 1, 64) + units[unit]
+}
+
+func parseTimestamp(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp expected in milliseconds or RFC3339: %s", value)
+	}
+	return parsed.UnixNano() / int64(time.Millisecond), nil
+}
+
+// parseReplicaAssignment parses the kafka-topics format: partitions separated by commas, broker ids of a
+// partition by colons.
+func parseReplicaAssignment(value string) ([][]int32, error) {
+	var result [][]int32
+	for i, item := range strings.Split(value, ",") {
+		var replicas []int32
+		for _, broker := range strings.Split(strings.TrimSpace(item), ":") {
+			id, err := strconv.ParseInt(broker, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("partition %d: invalid broker id %s", i, broker)
+			}
+			for _, replica := range replicas {
+				if replica == int32(id) {
+					return nil, fmt.Errorf("partition %d: broker %d is used twice", i, id)
+				}
+			}
+			replicas = append(replicas, int32(id))
+		}
+		if len(result) > 0 && len(replicas) != len(result[0]) {
+			return nil, fmt.Errorf("partition %d has %d replicas, partition 0 has %d", i, len(replicas), len(result[0]))
+		}
+		result = append(result, replicas)
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReplicaAssignment(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected [][]int32
+		isError  bool
+	}{
+		{value: "1", expected: [][]int32{{1}}},
+		{value: "1:2:3,2:3:1", expected: [][]int32{{1, 2, 3}, {2, 3, 1}}},
+		{value: "1:2, 3:4", expected: [][]int32{{1, 2}, {3, 4}}},
+		{value: "", isError: true},
+		{value: "1:2,", isError: true},
+		{value: ",1:2", isError: true},
+		{value: "1::2", isError: true},
+		{value: "1:a", isError: true},
+		{value: "1:2:1", isError: true},
+		{value: "1:2,3", isError: true},
+		{value: "1,2:3", isError: true},
+		{value: "1:99999999999", isError: true},
+	} {
+		result, err := parseReplicaAssignment(test.value)
+		if test.isError {
+			if err == nil {
+				t.Errorf("%q: error expected, got %v", test.value, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%q: got %v, expected %v", test.value, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"time"
+)
+
+type reassignmentPartition struct {
+	Topic     string   `json:"topic"`
+	Partition int32    `json:"partition"`
+	Replicas  []int32  `json:"replicas"`
+	LogDirs   []string `json:"log_dirs,omitempty"`
+}
+
+type reassignmentPlan struct {
+	Version    int                     `json:"version"`
+	Partitions []reassignmentPartition `json:"partitions"`
+}
+
+func readReassignmentPlan(fileName string) (kafkaadmin.Assignment, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var plan reassignmentPlan
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	result := make(kafkaadmin.Assignment)
+	for _, partition := range plan.Partitions {
+		if len(partition.Replicas) == 0 {
+			return nil, fmt.Errorf("%s: no replicas for %s/%d", fileName, partition.Topic, partition.Partition)
+		}
+		if result[partition.Topic] == nil {
+			result[partition.Topic] = make(map[int32][]int32)
+		}
+		result[partition.Topic][partition.Partition] = partition.Replicas
+	}
+	return result, nil
+}
+
+func writeReassignmentPlan(fileName string, assignment kafkaadmin.Assignment) error {
+	plan := reassignmentPlan{Version: 1, Partitions: []reassignmentPartition{}}
+	for _, topic := range sortedAssignmentTopics(assignment) {
+		for _, partition := range sortedAssignmentPartitions(assignment[topic]) {
+			plan.Partitions = append(plan.Partitions, reassignmentPartition{Topic: topic, Partition: partition, Replicas: assignment[topic][partition]})
+		}
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
+
+func sortedAssignmentTopics(assignment kafkaadmin.Assignment) []string {
+	var result []string
+	for topic := range assignment {
+		result = append(result, topic)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sortedAssignmentPartitions(partitions map[int32][]int32) []int32 {
+	var result []int32
+	for partition := range partitions {
+		result = append(result, partition)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func sameReplicas(left []int32, right []int32) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func changedAssignment(current kafkaadmin.Assignment, proposed kafkaadmin.Assignment) kafkaadmin.Assignment {
+	result := make(kafkaadmin.Assignment)
+	for topic, partitions := range proposed {
+		for partition, replicas := range partitions {
+			if sameReplicas(current[topic][partition], replicas) {
+				continue
+			}
+			if result[topic] == nil {
+				result[topic] = make(map[int32][]int32)
+			}
+			result[topic][partition] = replicas
+		}
+	}
+	return result
+}
+
+func printReassignment(current kafkaadmin.Assignment, changes kafkaadmin.Assignment) {
+	moved := 0
+	for _, topic := range sortedAssignmentTopics(changes) {
+		for _, partition := range sortedAssignmentPartitions(changes[topic]) {
+			replicas := changes[topic][partition]
+			fmt.Printf("%s/%d: %v -> %v\n", topic, partition, current[topic][partition], replicas)
+			for _, replica := range replicas {
+				if !inInt32Array(replica, current[topic][partition]) {
+					moved++
+				}
+			}
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Println("no partitions to move")
+		return
+	}
+	fmt.Printf("%d new replicas\n", moved)
+}
+
+func brokerSubset(client sarama.Client, ids []int) ([]kafkaadmin.BrokerRack, error) {
+	brokers := kafkaadmin.ClusterBrokers(client)
+	if len(ids) == 0 {
+		return brokers, nil
+	}
+	var result []kafkaadmin.BrokerRack
+	for _, id := range ids {
+		found := false
+		for _, broker := range brokers {
+			if broker.ID == int32(id) {
+				result = append(result, broker)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no broker %d in the cluster", id)
+		}
+	}
+	return result, nil
+}
+
+func proposeReassignment(client sarama.Client, topics []string, brokerIDs []int, replicationFactor int, rackAware bool) (kafkaadmin.Assignment, kafkaadmin.Assignment, error) {
+	brokers, err := brokerSubset(client, brokerIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := kafkaadmin.GetAssignment(client, topics...)
+	if err != nil {
+		return nil, nil, err
+	}
+	replicationFactors := make(map[string]int)
+	for topic, partitions := range current {
+		replicationFactors[topic] = replicationFactor
+		if replicationFactor > 0 {
+			continue
+		}
+		for _, replicas := range partitions {
+			if len(replicas) > replicationFactors[topic] {
+				replicationFactors[topic] = len(replicas)
+			}
+		}
+	}
+	proposed, err := kafkaadmin.BalanceAssignment(brokers, current, replicationFactors, rackAware)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, proposed, nil
+}
+
+type partitionReassignCmdType struct {
+	selector          topicSelector
+	planFileName      string
+	outputFileName    string
+	rollbackFileName  string
+	brokers           []int
+	replicationFactor int
+	isRackAware       bool
+	shouldExecute     bool
+	throttle          string
+	throttleFileName  string
+}
+
+func (p *partitionReassignCmdType) Run(cmd *cobra.Command, args []string) error {
+	var rate int64
+	if len(p.throttle) > 0 {
+		var err error
+		rate, err = parseBinarySize(p.throttle)
+		if err != nil || rate <= 0 {
+			return fmt.Errorf("invalid throttle %s", p.throttle)
+		}
+		if len(p.throttleFileName) == 0 {
+			return fmt.Errorf("--throttle requires --throttle-file to record the throttles for partition status --verify")
+		}
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var current, proposed kafkaadmin.Assignment
+	if len(p.planFileName) > 0 {
+		proposed, err = readReassignmentPlan(p.planFileName)
+		if err != nil {
+			return err
+		}
+		current, err = kafkaadmin.GetAssignment(client, sortedAssignmentTopics(proposed)...)
+	} else {
+		var topics []string
+		topics, _, err = p.selector.Select(client, args)
+		if err != nil {
+			return err
+		}
+		if len(topics) == 0 {
+			fmt.Println("no topics selected")
+			return nil
+		}
+		current, proposed, err = proposeReassignment(client, topics, p.brokers, p.replicationFactor, p.isRackAware)
+	}
+	if err != nil {
+		return err
+	}
+	changes := changedAssignment(current, proposed)
+	printReassignment(current, changes)
+	if len(p.outputFileName) > 0 {
+		err = writeReassignmentPlan(p.outputFileName, changes)
+		if err != nil {
+			return err
+		}
+	}
+	if len(p.rollbackFileName) > 0 {
+		rollback := make(kafkaadmin.Assignment)
+		for topic, partitions := range changes {
+			rollback[topic] = make(map[int32][]int32)
+			for partition := range partitions {
+				rollback[topic][partition] = current[topic][partition]
+			}
+		}
+		err = writeReassignmentPlan(p.rollbackFileName, rollback)
+		if err != nil {
+			return err
+		}
+	}
+	if !p.shouldExecute || len(changes) == 0 {
+		return nil
+	}
+	reassignAudit := newAudit(client)
+	if rate > 0 {
+		reassignAudit.describe(auditReassignment, "throttle", "%s/s, recorded in %s", formatBinarySize(rate), p.throttleFileName)
+	}
+	for _, topic := range sortedAssignmentTopics(changes) {
+		for _, partition := range sortedAssignmentPartitions(changes[topic]) {
+			reassignAudit.describe(auditReassignment, fmt.Sprintf("%s/%d", topic, partition), "replicas %v -> %v", current[topic][partition], changes[topic][partition])
+		}
+	}
+	err = reassignAudit.finish(p.execute(client, current, changes, rate))
+	if err != nil {
+		return err
+	}
+	fmt.Println("reassignment started, see partition status")
+	if rate > 0 {
+		fmt.Printf("remove the throttle when it completes with partition status --verify --throttle-file %s\n", p.throttleFileName)
+	}
+	return nil
+}
+
+func (p *partitionReassignCmdType) execute(client sarama.Client, current kafkaadmin.Assignment, changes kafkaadmin.Assignment, rate int64) error {
+	if rate > 0 {
+		record, err := setThrottles(client, current, changes, rate)
+		if record != nil {
+			recordErr := writeThrottleRecord(p.throttleFileName, record)
+			if err == nil {
+				err = recordErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return kafkaadmin.AlterReassignments(client, changes)
+}
+
+type partitionStatusCmdType struct {
+	shouldWait       bool
+	shouldVerify     bool
+	throttleFileName string
+	interval         time.Duration
+}
+
+func printReassignments(reassignments kafkaadmin.Reassignments, topics []string) int {
+	count := 0
+	for _, topic := range sortedReassignmentTopics(reassignments) {
+		if len(topics) > 0 && !inArray(topic, topics) {
+			continue
+		}
+		var partitions []int32
+		for partition := range reassignments[topic] {
+			partitions = append(partitions, partition)
+		}
+		sort.Slice(partitions, func(i, j int) bool {
+			return partitions[i] < partitions[j]
+		})
+		for _, partition := range partitions {
+			status := reassignments[topic][partition]
+			fmt.Printf("%s/%d: replicas %v adding %v removing %v\n", topic, partition, status.Replicas, status.AddingReplicas, status.RemovingReplicas)
+			count++
+		}
+	}
+	return count
+}
+
+func sortedReassignmentTopics(reassignments kafkaadmin.Reassignments) []string {
+	var result []string
+	for topic := range reassignments {
+		result = append(result, topic)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (p *partitionStatusCmdType) Run(cmd *cobra.Command, args []string) error {
+	var record *throttleRecord
+	if p.shouldVerify {
+		if len(p.throttleFileName) == 0 {
+			return fmt.Errorf("--verify requires --throttle-file")
+		}
+		var err error
+		record, err = readThrottleRecord(p.throttleFileName)
+		if err != nil {
+			return err
+		}
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for {
+		reassignments, err := kafkaadmin.ListReassignments(client)
+		if err != nil {
+			return err
+		}
+		count := printReassignments(reassignments, args)
+		if count == 0 {
+			fmt.Println("no reassignments in progress")
+		}
+		if count == 0 || !p.shouldWait {
+			if p.shouldVerify {
+				return clearThrottles(client, reassignments, record)
+			}
+			return nil
+		}
+		fmt.Printf("%d partitions in progress, %s\n\n", count, time.Now().Format("15:04:05"))
+		time.Sleep(p.interval)
+	}
+}
+
+type partitionCancelCmdType struct {
+	isAll bool
+}
+
+func (p *partitionCancelCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !p.isAll {
+		return fmt.Errorf("topics or --all expected")
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	reassignments, err := kafkaadmin.ListReassignments(client)
+	if err != nil {
+		return err
+	}
+	var selector topicSelector
+	cancels := make(kafkaadmin.Assignment)
+	for topic, partitions := range reassignments {
+		if !selector.matchesArgs(topic, args) {
+			continue
+		}
+		cancels[topic] = make(map[int32][]int32)
+		for partition := range partitions {
+			cancels[topic][partition] = nil
+		}
+	}
+	if len(cancels) == 0 {
+		fmt.Println("no reassignments to cancel")
+		return nil
+	}
+	cancelAudit := newAudit(client)
+	for _, topic := range sortedAssignmentTopics(cancels) {
+		for _, partition := range sortedAssignmentPartitions(cancels[topic]) {
+			cancelAudit.describe(auditReassignment, fmt.Sprintf("%s/%d", topic, partition), "cancelled")
+		}
+	}
+	err = cancelAudit.finish(kafkaadmin.AlterReassignments(client, cancels))
+	if err != nil {
+		return err
+	}
+	for _, topic := range sortedAssignmentTopics(cancels) {
+		fmt.Printf("%s: %d reassignments cancelled\n", topic, len(cancels[topic]))
+	}
+	return nil
+}
+
+var partitionReassignCmd = &cobra.Command{
+	Use:   "reassign [topics or glob patterns]",
+	Short: "move partition replicas between brokers or change the replication factor",
+	Long: "Generates a balanced, rack-aware assignment for the selected topics (or reads a kafka-reassign-partitions JSON plan) " +
+		"and prints the moves. Nothing is changed without --execute."}
+
+var partitionStatusCmd = &cobra.Command{
+	Use:   "status [topics]",
+	Short: "show reassignments in progress"}
+
+var partitionCancelCmd = &cobra.Command{
+	Use:   "cancel [topics or glob patterns]",
+	Short: "cancel reassignments in progress"}
+
+func init() {
+	var reassignRunner partitionReassignCmdType
+	partitionReassignCmd.RunE = reassignRunner.Run
+	flags := partitionReassignCmd.Flags()
+	reassignRunner.selector.addFlags(flags)
+	flags.StringVarP(&reassignRunner.planFileName, "plan-file", "f", "", "kafka-reassign-partitions JSON plan to execute instead of generating one")
+	flags.StringVarP(&reassignRunner.outputFileName, "output", "o", "", "write the plan of moved partitions to the file")
+	flags.StringVar(&reassignRunner.rollbackFileName, "rollback-file", "", "write the current assignment of moved partitions to the file")
+	flags.IntSliceVar(&reassignRunner.brokers, "brokers", nil, "brokers to place replicas on (all by default)")
+	flags.IntVar(&reassignRunner.replicationFactor, "replication-factor", 0, "new replication factor (kept by default)")
+	flags.BoolVar(&reassignRunner.isRackAware, "rack-aware", true, "place replicas of a partition on different racks")
+	flags.BoolVar(&reassignRunner.shouldExecute, "execute", false, "start the reassignment")
+	flags.StringVar(&reassignRunner.throttle, "throttle", "", "replication rate limit in bytes per second while moving, suffixes K,M,G,T supported")
+	flags.StringVar(&reassignRunner.throttleFileName, "throttle-file", "", "write the throttles added with --throttle to the file, partition status --verify removes them")
+	var statusRunner partitionStatusCmdType
+	partitionStatusCmd.RunE = statusRunner.Run
+	partitionStatusCmd.Flags().BoolVarP(&statusRunner.shouldWait, "wait", "w", false, "repeat until all reassignments are complete")
+	partitionStatusCmd.Flags().BoolVar(&statusRunner.shouldVerify, "verify", false, "remove replication throttles of completed reassignments recorded in --throttle-file")
+	partitionStatusCmd.Flags().StringVar(&statusRunner.throttleFileName, "throttle-file", "", "throttles written by partition reassign --throttle-file")
+	partitionStatusCmd.Flags().DurationVar(&statusRunner.interval, "interval", 5*time.Second, "interval between checks with --wait")
+	var cancelRunner partitionCancelCmdType
+	partitionCancelCmd.RunE = cancelRunner.Run
+	partitionCancelCmd.Flags().BoolVar(&cancelRunner.isAll, "all", false, "cancel every reassignment in progress")
+}
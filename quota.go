@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
+)
+
+var quotaByteKeys = []string{"producer_byte_rate", "consumer_byte_rate"}
+
+var quotaKeys = append(append([]string{}, quotaByteKeys...), "request_percentage", "controller_mutation_rate")
+
+type quotaRecord struct {
+	User     *string            `json:"user,omitempty" yaml:"user,omitempty"`
+	ClientID *string            `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+	Values   map[string]float64 `json:"values" yaml:"values"`
+}
+
+func newQuotaClient() (sarama.Client, error) {
+	client, err := kafkaadmin.NewVersionedClient([]string{hostPort.String()}, sarama.V2_6_0_0)
+	if err != nil {
+		return nil, err
+	}
+	err = kafkaadmin.RequireApis(client, kafkaadmin.DescribeClientQuotasKey, kafkaadmin.AlterClientQuotasKey)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func formatQuotaValue(key string, value float64) string {
+	if inArray(key, quotaByteKeys) {
+		return formatBinarySize(int64(value))
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func parseQuotaValue(key string, value string) (float64, error) {
+	if inArray(key, quotaByteKeys) {
+		result, err := parseBinarySize(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %s", key, value)
+		}
+		return float64(result), nil
+	}
+	if !inArray(key, quotaKeys) {
+		return 0, fmt.Errorf("unknown quota %s, expected one of %v", key, quotaKeys)
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s", key, value)
+	}
+	return result, nil
+}
+
+func writeQuotas(format string, quotas []*kafkaadmin.ClientQuota) error {
+	if format != "text" {
+		var records []*quotaRecord
+		for _, quota := range quotas {
+			records = append(records, &quotaRecord{User: quota.Entity.User, ClientID: quota.Entity.ClientID, Values: quota.Values})
+		}
+		return writeStructured(os.Stdout, format, records)
+	}
+	columns := append([]string{}, quotaKeys...)
+	for _, quota := range quotas {
+		for key := range quota.Values {
+			if !inArray(key, columns) {
+				columns = append(columns, key)
+			}
+		}
+	}
+	var rows [][]string
+	for _, quota := range quotas {
+		row := []string{formatQuotaEntityName(quota.Entity.User), formatQuotaEntityName(quota.Entity.ClientID)}
+		for _, key := range columns {
+			if value, ok := quota.Values[key]; ok {
+				row = append(row, formatQuotaValue(key, value))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return writeTable(os.Stdout, append([]string{"user", "client-id"}, columns...), rows)
+}
+
+func formatQuotaEntityName(name *string) string {
+	if name == nil {
+		return "-"
+	}
+	if len(*name) == 0 {
+		return "<default>"
+	}
+	return *name
+}
+
+type quotaEntityFlags struct {
+	user            string
+	clientID        string
+	isDefaultUser   bool
+	isDefaultClient bool
+}
+
+func (q *quotaEntityFlags) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&q.user, "user", "u", "", "user principal name")
+	flags.StringVarP(&q.clientID, "client-id", "c", "", "client id")
+	flags.BoolVar(&q.isDefaultUser, "default-user", false, "the default quota of users")
+	flags.BoolVar(&q.isDefaultClient, "default-client-id", false, "the default quota of client ids")
+}
+
+func (q *quotaEntityFlags) entity() (kafkaadmin.QuotaEntity, error) {
+	var result kafkaadmin.QuotaEntity
+	if len(q.user) > 0 && q.isDefaultUser {
+		return result, fmt.Errorf("--user and --default-user are mutually exclusive")
+	}
+	if len(q.clientID) > 0 && q.isDefaultClient {
+		return result, fmt.Errorf("--client-id and --default-client-id are mutually exclusive")
+	}
+	empty := ""
+	if len(q.user) > 0 {
+		result.User = &q.user
+	} else if q.isDefaultUser {
+		result.User = &empty
+	}
+	if len(q.clientID) > 0 {
+		result.ClientID = &q.clientID
+	} else if q.isDefaultClient {
+		result.ClientID = &empty
+	}
+	return result, nil
+}
+
+func (q *quotaEntityFlags) requiredEntity() (kafkaadmin.QuotaEntity, error) {
+	result, err := q.entity()
+	if err == nil && result.User == nil && result.ClientID == nil {
+		err = fmt.Errorf("--user, --client-id, --default-user or --default-client-id expected")
+	}
+	return result, err
+}
+
+type quotaDescribeCmdType struct {
+	entity quotaEntityFlags
+	output string
+}
+
+func (q *quotaDescribeCmdType) Run(cmd *cobra.Command, args []string) error {
+	entity, err := q.entity.entity()
+	if err != nil {
+		return err
+	}
+	client, err := newQuotaClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	quotas, err := kafkaadmin.DescribeClientQuotas(client, entity, false)
+	if err != nil {
+		return err
+	}
+	return writeQuotas(q.output, quotas)
+}
+
+type quotaSetCmdType struct {
+	entity   quotaEntityFlags
+	isDryRun bool
+}
+
+func (q *quotaSetCmdType) Run(cmd *cobra.Command, args []string) error {
+	entity, err := q.entity.requiredEntity()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("key=value pairs expected, keys: %v", quotaKeys)
+	}
+	values := make(map[string]float64)
+	var keys []string
+	for _, arg := range args {
+		key, value, err := parseKeyValue(arg)
+		if err != nil {
+			return err
+		}
+		values[key], err = parseQuotaValue(key, value)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	client, err := newQuotaClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if q.isDryRun {
+		err = kafkaadmin.AlterClientQuotas(client, entity, values, nil, true)
+	} else {
+		setAudit := newAudit(client)
+		for _, key := range keys {
+			setAudit.describe(auditQuota, entity.String(), "%s=%s", key, formatQuotaValue(key, values[key]))
+		}
+		err = setAudit.finish(kafkaadmin.AlterClientQuotas(client, entity, values, nil, false))
+	}
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Printf("%s %s=%s\n", entity, key, formatQuotaValue(key, values[key]))
+	}
+	return nil
+}
+
+type quotaDeleteCmdType struct {
+	entity quotaEntityFlags
+}
+
+func (q *quotaDeleteCmdType) Run(cmd *cobra.Command, args []string) error {
+	entity, err := q.entity.requiredEntity()
+	if err != nil {
+		return err
+	}
+	client, err := newQuotaClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	keys := args
+	if len(keys) == 0 {
+		quotas, err := kafkaadmin.DescribeClientQuotas(client, entity, true)
+		if err != nil {
+			return err
+		}
+		for _, quota := range quotas {
+			for key := range quota.Values {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			fmt.Println("no quotas set for", entity)
+			return nil
+		}
+		sort.Strings(keys)
+	}
+	deleteAudit := newAudit(client)
+	for _, key := range keys {
+		deleteAudit.describe(auditQuota, entity.String(), "%s removed", key)
+	}
+	err = deleteAudit.finish(kafkaadmin.AlterClientQuotas(client, entity, nil, keys, false))
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Printf("%s %s removed\n", entity, key)
+	}
+	return nil
+}
+
+var quotaDescribeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "describe client quotas, all by default, an entity flag limits the output to entities having that component"}
+
+var quotaSetCmd = &cobra.Command{
+	Use:   "set key=value...",
+	Short: "set quotas of a user, client id or both: producer_byte_rate, consumer_byte_rate (K/M/G accepted), request_percentage, controller_mutation_rate"}
+
+var quotaDeleteCmd = &cobra.Command{
+	Use:   "delete [keys]",
+	Short: "remove quotas of a user, client id or both, all of them if no keys are given"}
+
+func init() {
+	var describeRunner quotaDescribeCmdType
+	quotaDescribeCmd.RunE = describeRunner.Run
+	describeRunner.entity.addFlags(quotaDescribeCmd.Flags())
+	quotaDescribeCmd.Flags().StringVarP(&describeRunner.output, "output", "o", "text", "output format: text, json or yaml")
+	var setRunner quotaSetCmdType
+	quotaSetCmd.RunE = setRunner.Run
+	setRunner.entity.addFlags(quotaSetCmd.Flags())
+	quotaSetCmd.Flags().BoolVar(&setRunner.isDryRun, "dry-run", false, "validate the change on the broker without applying")
+	var deleteRunner quotaDeleteCmdType
+	quotaDeleteCmd.RunE = deleteRunner.Run
+	deleteRunner.entity.addFlags(quotaDeleteCmd.Flags())
+}
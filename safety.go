@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/pflag"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var builtinProtectedTopics = []string{"__*"}
+
+func protectedTopics(topics []string) ([]string, error) {
+	config, err := loadToolConfig()
+	if err != nil {
+		return nil, err
+	}
+	patterns := append(append([]string{}, builtinProtectedTopics...), config.ProtectedTopics...)
+	var result []string
+	for _, topic := range topics {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, topic); matched {
+				result = append(result, topic)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func confirmTyped(prompt string, expected string) bool {
+	fmt.Printf("%s, type %s to confirm: ", prompt, expected)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == expected
+}
+
+type destructiveGuard struct {
+	isAssumeYes bool
+	isForced    bool
+}
+
+func (g *destructiveGuard) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&g.isAssumeYes, "yes", "y", false, "do not ask for confirmation")
+	flags.BoolVar(&g.isForced, "force", false, "allow protected topics and topics consumed by active groups")
+}
+
+// check refuses protected topics and, if checkGroups is set, topics with active consumer groups unless forced,
+// then asks to type the topic name (or the number of topics) unless --yes is given.
+func (g *destructiveGuard) check(client sarama.Client, action string, topics []string, checkGroups bool) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	if !g.isForced {
+		protected, err := protectedTopics(topics)
+		if err != nil {
+			return err
+		}
+		if len(protected) > 0 {
+			return fmt.Errorf("protected topics cannot be %s without --force: %s", action, strings.Join(protected, ", "))
+		}
+		if checkGroups {
+			active, err := kafkaadmin.ActiveGroupTopics(client)
+			if err != nil {
+				return err
+			}
+			var messages []string
+			for _, topic := range topics {
+				if groups, ok := active[topic]; ok {
+					sort.Strings(groups)
+					messages = append(messages, fmt.Sprintf("%s (%s)", topic, strings.Join(groups, ", ")))
+				}
+			}
+			if len(messages) > 0 {
+				return fmt.Errorf("topics with active consumer groups cannot be %s without --force: %s", action, strings.Join(messages, "; "))
+			}
+		}
+	}
+	if g.isAssumeYes {
+		return nil
+	}
+	expected := topics[0]
+	prompt := fmt.Sprintf("topic %s will be %s", topics[0], action)
+	if len(topics) > 1 {
+		expected = strconv.Itoa(len(topics))
+		prompt = fmt.Sprintf("%d topics will be %s: %s", len(topics), action, strings.Join(topics, ", "))
+	}
+	if !confirmTyped(prompt, expected) {
+		return fmt.Errorf("aborted")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	leaderThrottledRateName       = "leader.replication.throttled.rate"
+	followerThrottledRateName     = "follower.replication.throttled.rate"
+	leaderThrottledReplicasName   = "leader.replication.throttled.replicas"
+	followerThrottledReplicasName = "follower.replication.throttled.replicas"
+)
+
+func throttledReplicas(replicas map[int32][]int32) string {
+	var items []string
+	for _, partition := range sortedAssignmentPartitions(replicas) {
+		for _, replica := range replicas[partition] {
+			items = append(items, fmt.Sprintf("%d:%d", partition, replica))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// throttleRecord keeps the throttle entries a reassignment added and the broker rates it replaced, so that cleanup
+// leaves throttles of other operations alone.
+type throttleRecord struct {
+	Topics map[string]map[string]string `json:"topics"`
+	Rates  map[string]map[string]string `json:"rates"`
+}
+
+func readThrottleRecord(fileName string) (*throttleRecord, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var result throttleRecord
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return &result, nil
+}
+
+func writeThrottleRecord(fileName string, record *throttleRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
+
+func listChange(operation sarama.IncrementalAlterConfigsOperation, items []string) sarama.IncrementalAlterConfigsEntry {
+	value := strings.Join(items, ",")
+	return sarama.IncrementalAlterConfigsEntry{Operation: operation, Value: &value}
+}
+
+func splitThrottledReplicas(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// setThrottles throttles replication from the current replicas of moved partitions (leader side) to the new ones
+// (follower side) and limits the rate on every broker taking part. Entries are appended to existing throttles, the
+// returned record holds what was added.
+func setThrottles(client sarama.Client, current kafkaadmin.Assignment, changes kafkaadmin.Assignment, rate int64) (*throttleRecord, error) {
+	topics := sortedAssignmentTopics(changes)
+	configs, err := kafkaadmin.DynamicConfigs(client, sarama.TopicResource, topics...)
+	if err != nil {
+		return nil, err
+	}
+	record := &throttleRecord{Topics: make(map[string]map[string]string), Rates: make(map[string]map[string]string)}
+	brokers := make(map[int32]bool)
+	for _, topic := range topics {
+		leaders := make(map[int32][]int32)
+		followers := make(map[int32][]int32)
+		for partition, replicas := range changes[topic] {
+			leaders[partition] = current[topic][partition]
+			for _, replica := range current[topic][partition] {
+				brokers[replica] = true
+			}
+			for _, replica := range replicas {
+				brokers[replica] = true
+				if !inInt32Array(replica, current[topic][partition]) {
+					followers[partition] = append(followers[partition], replica)
+				}
+			}
+		}
+		changed := make(kafkaadmin.ConfigChanges)
+		added := make(map[string]string)
+		for name, value := range map[string]string{leaderThrottledReplicasName: throttledReplicas(leaders), followerThrottledReplicasName: throttledReplicas(followers)} {
+			existing := splitThrottledReplicas(configs[topic][name])
+			if inArray("*", existing) {
+				continue //every replica is throttled already
+			}
+			var items []string
+			for _, item := range splitThrottledReplicas(value) {
+				if !inArray(item, existing) {
+					items = append(items, item)
+				}
+			}
+			if len(items) == 0 {
+				continue
+			}
+			changed[name] = listChange(sarama.IncrementalAlterConfigsOperationAppend, items)
+			added[name] = strings.Join(items, ",")
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		err = kafkaadmin.AlterConfigs(client, sarama.TopicResource, changed, false, topic)
+		if err != nil {
+			return record, fmt.Errorf("throttling %s: %w", topic, err)
+		}
+		record.Topics[topic] = added
+	}
+	var ids []int32
+	var names []string
+	for id := range brokers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		names = append(names, strconv.Itoa(int(id)))
+	}
+	brokerConfigs, err := kafkaadmin.DynamicConfigs(client, sarama.BrokerResource, names...)
+	if err != nil {
+		return record, err
+	}
+	value := strconv.FormatInt(rate, 10)
+	rates := kafkaadmin.SetConfigChanges(map[string]string{leaderThrottledRateName: value, followerThrottledRateName: value}, nil)
+	for _, name := range names {
+		err = kafkaadmin.AlterConfigs(client, sarama.BrokerResource, rates, false, name)
+		if err != nil {
+			return record, fmt.Errorf("throttling broker %s: %w", name, err)
+		}
+		record.Rates[name] = make(map[string]string)
+		for _, key := range []string{leaderThrottledRateName, followerThrottledRateName} {
+			if previous, ok := brokerConfigs[name][key]; ok {
+				record.Rates[name][key] = previous
+			}
+		}
+	}
+	fmt.Printf("replication throttled to %s/s on brokers %v\n", formatBinarySize(rate), ids)
+	return record, nil
+}
+
+// clearThrottles subtracts the recorded throttle entries of topics without reassignments in progress and restores
+// the broker rates once none of the recorded topics is reassigned.
+func clearThrottles(client sarama.Client, reassignments kafkaadmin.Reassignments, record *throttleRecord) error {
+	var topics []string
+	for topic := range record.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	existing, err := kafkaadmin.ClusterAssignment(client)
+	if err != nil {
+		return err
+	}
+	var present []string
+	for _, topic := range topics {
+		if _, ok := existing[topic]; ok {
+			present = append(present, topic)
+		}
+	}
+	configs, err := kafkaadmin.DynamicConfigs(client, sarama.TopicResource, present...)
+	if err != nil {
+		return err
+	}
+	inProgress := false
+	for _, topic := range present {
+		if len(reassignments[topic]) > 0 {
+			fmt.Printf("%s: reassignment in progress, throttle kept\n", topic)
+			inProgress = true
+			continue
+		}
+		changed := make(kafkaadmin.ConfigChanges)
+		for name, value := range record.Topics[topic] {
+			added := splitThrottledReplicas(value)
+			var kept []string
+			for _, item := range splitThrottledReplicas(configs[topic][name]) {
+				if !inArray(item, added) {
+					kept = append(kept, item)
+				}
+			}
+			if len(kept) == len(splitThrottledReplicas(configs[topic][name])) {
+				continue
+			}
+			if len(kept) == 0 {
+				changed[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+			} else {
+				changed[name] = listChange(sarama.IncrementalAlterConfigsOperationSubtract, added)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		err = kafkaadmin.AlterConfigs(client, sarama.TopicResource, changed, false, topic)
+		if err != nil {
+			return fmt.Errorf("%s: %w", topic, err)
+		}
+		fmt.Printf("%s: throttle removed\n", topic)
+	}
+	if inProgress {
+		return nil
+	}
+	var names []string
+	for name := range record.Rates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set := make(map[string]string)
+		var unset []string
+		for _, key := range []string{leaderThrottledRateName, followerThrottledRateName} {
+			if previous, ok := record.Rates[name][key]; ok {
+				set[key] = previous
+			} else {
+				unset = append(unset, key)
+			}
+		}
+		err = kafkaadmin.AlterConfigs(client, sarama.BrokerResource, kafkaadmin.SetConfigChanges(set, unset), false, name)
+		if err != nil {
+			return fmt.Errorf("broker %s: %w", name, err)
+		}
+		fmt.Printf("broker %s: throttle removed\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+)
+
+type topicDescribeCmdType struct {
+	shouldPrintDefault bool
+	selector           topicSelector
+}
+
+func (t *topicDescribeCmdType) Run(cmd *cobra.Command, args []string) error {
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	names, topics, err := t.selector.Select(client, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("no topics selected")
+		return nil
+	}
+	configs, err := kafkaadmin.GetTopicConfigs(client, names...)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		topic := topics[name]
+		fmt.Println(name)
+		if topic.IsInternal {
+			fmt.Println("\tinternal")
+		}
+		fmt.Println("\tPartitions:")
+		for _, partition := range topic.Partitions {
+			fmt.Printf("\t\t%d leader %d replicas %v isr %v", partition.ID, partition.Leader, partition.Replicas, partition.Isr)
+			if len(partition.OfflineReplicas) > 0 {
+				fmt.Printf(" offline %v", partition.OfflineReplicas)
+			}
+			fmt.Println("")
+		}
+		fmt.Println("\tConfigs:")
+		for _, key := range sortedKeys(configValues(configs[name])) {
+			config := configs[name][key]
+			if !t.shouldPrintDefault && config.Default {
+				continue
+			}
+			fmt.Printf("\t\t%s=%s (%s)\n", config.Name, config.Value, config.Source)
+		}
+	}
+	return nil
+}
+
+func configValues(configs kafkaadmin.RawConfig) map[string]string {
+	result := make(map[string]string)
+	for name, config := range configs {
+		result[name] = config.Value
+	}
+	return result
+}
+
+var topicDescribeCmd = &cobra.Command{
+	Use:     "describe [topics or glob patterns]",
+	Aliases: []string{"d"},
+	Short:   "show partition replicas, leaders and configs of topics"}
+
+func init() {
+	var runner topicDescribeCmdType
+	topicDescribeCmd.RunE = runner.Run
+	flags := topicDescribeCmd.Flags()
+	flags.BoolVarP(&runner.shouldPrintDefault, "default", "d", false, "print unchanged (default) config parameters")
+	runner.selector.addFlags(flags)
+}
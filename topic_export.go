@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type strimziTopicMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type strimziTopicSpec struct {
+	TopicName  string            `yaml:"topicName"`
+	Partitions int32             `yaml:"partitions"`
+	Replicas   int16             `yaml:"replicas"`
+	Config     map[string]string `yaml:"config,omitempty"`
+}
+
+type strimziTopic struct {
+	APIVersion string               `yaml:"apiVersion"`
+	Kind       string               `yaml:"kind"`
+	Metadata   strimziTopicMetadata `yaml:"metadata"`
+	Spec       strimziTopicSpec     `yaml:"spec"`
+}
+
+var invalidResourceNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+var invalidTerraformNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func strimziResourceName(topic string) string {
+	name := invalidResourceNameChars.ReplaceAllString(strings.ToLower(topic), "-")
+	name = strings.Trim(name, "-")
+	if len(name) == 0 {
+		name = "topic"
+	}
+	return name
+}
+
+func terraformResourceName(topic string) string {
+	name := invalidTerraformNameChars.ReplaceAllString(topic, "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	return name
+}
+
+// checkResourceNames fails when different topics map to the same resource name, like a.b and a_b for Terraform.
+func checkResourceNames(spec *topicsSpec, resourceName func(string) string) error {
+	topics := make(map[string]string)
+	var messages []string
+	for _, topic := range spec.Topics {
+		name := resourceName(topic.Name)
+		if other, ok := topics[name]; ok {
+			messages = append(messages, fmt.Sprintf("topics %s and %s both map to the resource name %s", other, topic.Name, name))
+			continue
+		}
+		topics[name] = topic.Name
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+func writeStrimziTopics(writer io.Writer, clusterName string, spec *topicsSpec) error {
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+	for _, topic := range spec.Topics {
+		err := encoder.Encode(&strimziTopic{
+			APIVersion: "kafka.strimzi.io/v1beta2",
+			Kind:       "KafkaTopic",
+			Metadata: strimziTopicMetadata{
+				Name:   strimziResourceName(topic.Name),
+				Labels: map[string]string{"strimzi.io/cluster": clusterName}},
+			Spec: strimziTopicSpec{
+				TopicName:  topic.Name,
+				Partitions: topic.Partitions,
+				Replicas:   topic.ReplicationFactor,
+				Config:     topic.Configs}})
+		if err != nil {
+			return err
+		}
+	}
+	return encoder.Close()
+}
+
+func writeTerraformTopics(writer io.Writer, spec *topicsSpec) error {
+	for i, topic := range spec.Topics {
+		if i > 0 {
+			fmt.Fprintln(writer, "")
+		}
+		fmt.Fprintf(writer, "resource \"kafka_topic\" %s {\n", strconv.Quote(terraformResourceName(topic.Name)))
+		fmt.Fprintf(writer, "  name               = %s\n", strconv.Quote(topic.Name))
+		fmt.Fprintf(writer, "  replication_factor = %d\n", topic.ReplicationFactor)
+		fmt.Fprintf(writer, "  partitions         = %d\n", topic.Partitions)
+		if len(topic.Configs) > 0 {
+			fmt.Fprintln(writer, "\n  config = {")
+			for _, key := range sortedKeys(topic.Configs) {
+				fmt.Fprintf(writer, "    %s = %s\n", strconv.Quote(key), strconv.Quote(topic.Configs[key]))
+			}
+			fmt.Fprintln(writer, "  }")
+		}
+		_, err := fmt.Fprintln(writer, "}")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type topicExportCmdType struct {
+	client                 sarama.Client
+	fileName               string
+	format                 string
+	selector               topicSelector
+	clusterName            string
+	shouldExportAssignment bool
+}
+
+// exportedConfigs returns the configs set on the topic itself, defaults and broker-wide settings are left out.
+// Sensitive values are not returned by brokers and are skipped with a warning.
+func exportedConfigs(topic string, configs kafkaadmin.RawConfig) map[string]string {
+	var names []string
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make(map[string]string)
+	for _, name := range names {
+		config := configs[name]
+		if config.Default || config.ReadOnly || config.Source != sarama.SourceTopic {
+			continue
+		}
+		if config.Sensitive {
+			fmt.Fprintf(os.Stderr, "warning: %s: sensitive config %s is not exported\n", topic, name)
+			continue
+		}
+		result[name] = config.Value
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+func (t *topicExportCmdType) Run(cmd *cobra.Command, args []string) error {
+	var err error
+	t.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer t.client.Close()
+	names, topics, err := t.selector.Select(t.client, args)
+	if err != nil {
+		return err
+	}
+	spec := &topicsSpec{}
+	if len(names) > 0 {
+		configs, err := kafkaadmin.GetTopicConfigs(t.client, names...)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			topic := topics[name]
+			exported := &topicSpec{
+				Name:       name,
+				Partitions: int32(len(topic.Partitions)),
+				Configs:    exportedConfigs(name, configs[name])}
+			if len(topic.Partitions) > 0 {
+				exported.ReplicationFactor = int16(len(topic.Partitions[0].Replicas))
+			}
+			if t.shouldExportAssignment {
+				exported.ReplicaAssignment = make(map[int32][]int32)
+				for _, partition := range topic.Partitions {
+					exported.ReplicaAssignment[partition.ID] = partition.Replicas
+				}
+			}
+			spec.Topics = append(spec.Topics, exported)
+		}
+	}
+	format := t.format
+	if len(format) == 0 && len(t.fileName) == 0 {
+		format = "yaml"
+	}
+	format = detectFormat(format, t.fileName)
+	switch format {
+	case "strimzi":
+		err = checkResourceNames(spec, strimziResourceName)
+	case "terraform":
+		err = checkResourceNames(spec, terraformResourceName)
+	}
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = os.Stdout
+	if len(t.fileName) > 0 {
+		file, err := os.Create(t.fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	switch format {
+	case "yaml", "json":
+		return writeStructured(writer, format, spec)
+	case "strimzi":
+		return writeStrimziTopics(writer, t.clusterName, spec)
+	case "terraform":
+		return writeTerraformTopics(writer, spec)
+	}
+	return fmt.Errorf("unsupported format %s", format)
+}
+
+var topicExportCmd = &cobra.Command{
+	Use:     "export [topics or glob patterns]",
+	Aliases: []string{"e"},
+	Short:   "write topics as a spec for apply, Strimzi KafkaTopic resources or Terraform"}
+
+func init() {
+	var runner topicExportCmdType
+	topicExportCmd.RunE = runner.Run
+	flags := topicExportCmd.Flags()
+	flags.StringVarP(&runner.fileName, "file", "f", "", "output file (stdout if not set)")
+	flags.StringVarP(&runner.format, "format", "F", "", "output format: yaml, json, strimzi or terraform (detected by file extension if not set)")
+	runner.selector.addFlags(flags)
+	flags.StringVar(&runner.clusterName, "strimzi-cluster", "my-cluster", "strimzi.io/cluster label for KafkaTopic resources")
+	flags.BoolVar(&runner.shouldExportAssignment, "assignment", false, "include the current replica assignment")
+}
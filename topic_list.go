@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"github.com/Shopify/sarama"
+	"github.com/IBM/sarama"
 	"github.com/spf13/cobra"
 	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +18,10 @@ type topicListCmdType struct {
 	shouldShowCompactedOnly bool
 	shouldShowInfiniteOnly  bool
 	verbose                 bool
+	selector                topicSelector
+	output                  string
+	columns                 []string
+	shouldShowSize          bool
 }
 
 func inArray(target string, array []string) bool {
@@ -45,7 +53,273 @@ func isInfinite(configs kafkaadmin.RawConfig) bool {
 		configs["retention.bytes"].Value == "-1"
 }
 
+type topicListRange struct {
+	Oldest int64 `json:"oldest" yaml:"oldest"`
+	Newest int64 `json:"newest" yaml:"newest"`
+}
+
+type topicListPartition struct {
+	ID       int32           `json:"id" yaml:"id"`
+	Leader   int32           `json:"leader" yaml:"leader"`
+	Writable bool            `json:"writable" yaml:"writable"`
+	Range    *topicListRange `json:"range,omitempty" yaml:"range,omitempty"`
+	Error    string          `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type topicListConfig struct {
+	Name     string `json:"name" yaml:"name"`
+	Value    string `json:"value" yaml:"value"`
+	Default  bool   `json:"default" yaml:"default"`
+	ReadOnly bool   `json:"readonly" yaml:"readonly"`
+}
+
+type topicListEntry struct {
+	Name       string               `json:"name" yaml:"name"`
+	Partitions []topicListPartition `json:"partitions" yaml:"partitions"`
+	Configs    []topicListConfig    `json:"configs" yaml:"configs"`
+	Size       *int64               `json:"size,omitempty" yaml:"size,omitempty"`
+	allConfigs kafkaadmin.RawConfig
+}
+
+func (e *topicListEntry) config(name string) string {
+	if config, ok := e.allConfigs[name]; ok {
+		return config.Value
+	}
+	return ""
+}
+
+func (e *topicListEntry) messages() int64 {
+	var result int64
+	for _, partition := range e.Partitions {
+		if partition.Range != nil {
+			result += partition.Range.Newest - partition.Range.Oldest
+		}
+	}
+	return result
+}
+
+var topicListColumns = map[string]func(entry *topicListEntry) string{
+	"name": func(entry *topicListEntry) string {
+		return entry.Name
+	},
+	"partitions": func(entry *topicListEntry) string {
+		return strconv.Itoa(len(entry.Partitions))
+	},
+	"writable": func(entry *topicListEntry) string {
+		count := 0
+		for _, partition := range entry.Partitions {
+			if partition.Writable {
+				count++
+			}
+		}
+		return strconv.Itoa(count)
+	},
+	"messages": func(entry *topicListEntry) string {
+		return strconv.FormatInt(entry.messages(), 10)
+	},
+	"leaders": func(entry *topicListEntry) string {
+		var leaders []string
+		for _, partition := range entry.Partitions {
+			leader := strconv.Itoa(int(partition.Leader))
+			if !inArray(leader, leaders) {
+				leaders = append(leaders, leader)
+			}
+		}
+		return strings.Join(leaders, ",")
+	},
+	"size": func(entry *topicListEntry) string {
+		if entry.Size == nil {
+			return ""
+		}
+		return strconv.FormatInt(*entry.Size, 10)
+	}}
+
+var (
+	tableColumns = []string{"name", "partitions", "messages"}
+	wideColumns  = []string{"name", "partitions", "writable", "leaders", "messages", cleanupPolicyName, retentionMsName, retentionBytesName}
+)
+
+func topicListColumn(name string) func(entry *topicListEntry) string {
+	if column, ok := topicListColumns[name]; ok {
+		return column
+	}
+	return func(entry *topicListEntry) string {
+		return entry.config(name)
+	}
+}
+
+const progressPartitionsThreshold = 500
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (t *topicListCmdType) getRanges(topics []string, metadata map[string]*sarama.TopicMetadata) (kafkaadmin.TopicRanges, kafkaadmin.PartitionErrors) {
+	partitions := make(kafkaadmin.TopicPartitions)
+	total := 0
+	for _, topic := range topics {
+		for _, partition := range metadata[topic].Partitions {
+			partitions[topic] = append(partitions[topic], partition.ID)
+			total++
+		}
+	}
+	var progress func(done int, total int)
+	if total >= progressPartitionsThreshold && isTerminal(os.Stderr) {
+		progress = func(done int, total int) {
+			fmt.Fprintf(os.Stderr, "\rfetching offsets: %d/%d partitions", done, total)
+		}
+		defer fmt.Fprintln(os.Stderr, "")
+	}
+	ranges, err := kafkaadmin.GetTopicRanges(t.client, partitions, progress)
+	var partitionErrors kafkaadmin.PartitionErrors
+	if errors.As(err, &partitionErrors) {
+		fmt.Fprintln(os.Stderr, "offsets lookup failed, messages of these partitions are not counted:")
+		fmt.Fprintln(os.Stderr, partitionErrors)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "offsets lookup failed:", err)
+	}
+	return ranges, partitionErrors
+}
+
+func (t *topicListCmdType) getSizes(topics []string) map[string]int64 {
+	logDirs, err := kafkaadmin.DescribeLogDirs(t.client, topics...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "log dirs lookup failed:", err)
+		return nil
+	}
+	return kafkaadmin.TopicSizes(logDirs)
+}
+
+func (t *topicListCmdType) collectTopics(topics []string, metadata map[string]*sarama.TopicMetadata, configs kafkaadmin.TopicsConfigs) []*topicListEntry {
+	var result []*topicListEntry
+	ranges, rangeErrors := t.getRanges(topics, metadata)
+	var sizes map[string]int64
+	if t.shouldShowSize || inArray("size", t.columns) {
+		sizes = t.getSizes(topics)
+	}
+	for _, topic := range topics {
+		entry := &topicListEntry{Name: topic, Partitions: []topicListPartition{}, Configs: []topicListConfig{}, allConfigs: configs[topic]}
+		result = append(result, entry)
+		if size, ok := sizes[topic]; ok {
+			entry.Size = &size
+		}
+		writables, err := t.client.WritablePartitions(topic)
+		if err != nil {
+			writables = nil
+		}
+		partitions := metadata[topic].Partitions
+		sort.Slice(partitions, func(i, j int) bool {
+			return partitions[i].ID < partitions[j].ID
+		})
+		for _, partition := range partitions {
+			item := topicListPartition{ID: partition.ID, Leader: partition.Leader}
+			for _, writable := range writables {
+				if partition.ID == writable {
+					item.Writable = true
+					break
+				}
+			}
+			if offsetRange, ok := ranges[topic][partition.ID]; ok {
+				item.Range = &topicListRange{Oldest: offsetRange.Oldest, Newest: offsetRange.Newest}
+			}
+			if rangeErr, ok := rangeErrors[topic][partition.ID]; ok {
+				item.Error = rangeErr.Error()
+			}
+			entry.Partitions = append(entry.Partitions, item)
+		}
+		var names []string
+		for name := range configs[topic] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			config := configs[topic][name]
+			if !t.shouldPrintDefault && config.Default {
+				continue
+			}
+			entry.Configs = append(entry.Configs, topicListConfig{
+				Name:     config.Name,
+				Value:    config.Value,
+				Default:  config.Default,
+				ReadOnly: config.ReadOnly})
+		}
+	}
+	return result
+}
+
+func (t *topicListCmdType) printText(entries []*topicListEntry) {
+	fmt.Println(len(entries), "topics:")
+	for _, entry := range entries {
+		fmt.Println(entry.Name)
+		if entry.Size != nil {
+			fmt.Println("\tSize:", formatBinarySize(*entry.Size))
+		}
+		fmt.Println("\tPartitions:")
+		for _, partition := range entry.Partitions {
+			fmt.Printf("\t\t%d ", partition.ID)
+			if partition.Writable {
+				fmt.Printf("(writable) ")
+			}
+			if partition.Range != nil {
+				fmt.Printf("range %d-%d", partition.Range.Oldest, partition.Range.Newest)
+			}
+			if len(partition.Error) > 0 {
+				fmt.Printf("offsets: %s", partition.Error)
+			}
+			fmt.Println("")
+		}
+		if !t.verbose {
+			continue
+		}
+		fmt.Println("\t Configs")
+		for _, config := range entry.Configs {
+			fmt.Printf("\t\t %s=%s", config.Name, config.Value)
+			if config.Default && !config.ReadOnly {
+				fmt.Printf(" (default) ")
+			}
+			if config.ReadOnly && !config.Default {
+				fmt.Printf(" (readonly) ")
+			}
+			if config.Default && config.ReadOnly {
+				fmt.Printf(" (readonly,default) ")
+			}
+			fmt.Println("")
+		}
+	}
+}
+
+func (t *topicListCmdType) printColumns(entries []*topicListEntry) error {
+	columns := t.columns
+	if len(columns) == 0 {
+		columns = tableColumns
+		if t.output == "wide" || t.output == "csv" {
+			columns = wideColumns
+		}
+		if t.shouldShowSize {
+			columns = append(append([]string{}, columns...), "size")
+		}
+	}
+	var rows [][]string
+	for _, entry := range entries {
+		var row []string
+		for _, column := range columns {
+			row = append(row, topicListColumn(column)(entry))
+		}
+		rows = append(rows, row)
+	}
+	if t.output == "csv" {
+		return writeCSV(os.Stdout, columns, rows)
+	}
+	return writeTable(os.Stdout, columns, rows)
+}
+
 func (t *topicListCmdType) runListTopics(cmd *cobra.Command, args []string) error {
+	switch t.output {
+	case "", "text", "json", "yaml", "csv", "table", "wide":
+	default:
+		return fmt.Errorf("unsupported output %s", t.output)
+	}
 	var err error
 	t.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
 	if err != nil {
@@ -56,15 +330,10 @@ func (t *topicListCmdType) runListTopics(cmd *cobra.Command, args []string) erro
 	if err != nil {
 		return err
 	}
-	topics, err := t.client.Topics()
+	topics, metadata, err := t.selector.Select(t.client, args)
 	if err != nil {
 		return err
 	}
-	if len(args) > 0 {
-		topics = filterTopics(topics, func(topic string) bool {
-			return inArray(topic, args)
-		})
-	}
 	configs, err := kafkaadmin.GetTopicConfigs(t.client, topics...)
 	if err != nil {
 		return err
@@ -79,57 +348,22 @@ func (t *topicListCmdType) runListTopics(cmd *cobra.Command, args []string) erro
 			return isInfinite(configs[topic])
 		})
 	}
-	fmt.Println(len(topics), "topics:")
-	for _, topic := range topics {
-		fmt.Println(topic)
-		partitions, err := t.client.Partitions(topic)
-		if err == nil {
-			writables, err := t.client.WritablePartitions(topic)
-			if err != nil {
-				writables = nil
-			}
-			fmt.Println("\tPartitions:")
-			for _, partition := range partitions {
-				fmt.Printf("\t\t%d ", partition)
-				for _, writable := range writables {
-					if partition == writable {
-						fmt.Printf("(writable) ")
-						break
-					}
-				}
-				min, max, err := kafkaadmin.GetTopicRange(t.client, topic, partition)
-				if err == nil {
-					fmt.Printf("range %d-%d", min, max)
-				}
-				fmt.Println("")
-			}
-			if !t.verbose {
-				continue
-			}
-			fmt.Println("\t Configs")
-			for _, config := range configs[topic] {
-				if !t.shouldPrintDefault && config.Default {
-					continue
-				}
-				fmt.Printf("\t\t %s=%s", config.Name, config.Value)
-				if config.Default && !config.ReadOnly {
-					fmt.Printf(" (default) ")
-				}
-				if config.ReadOnly && !config.Default {
-					fmt.Println(" (readonly) ")
-				}
-				if config.Default && config.ReadOnly {
-					fmt.Println(" (readonly,default) ")
-				}
-				fmt.Println("")
-			}
+	entries := t.collectTopics(topics, metadata, configs)
+	switch t.output {
+	case "json", "yaml":
+		if entries == nil {
+			entries = []*topicListEntry{}
 		}
+		return writeStructured(os.Stdout, t.output, entries)
+	case "csv", "table", "wide":
+		return t.printColumns(entries)
 	}
+	t.printText(entries)
 	return nil
 }
 
 var topicListCmd = &cobra.Command{
-	Use:     "list",
+	Use:     "list [topics or glob patterns]",
 	Aliases: []string{"l"},
 	Short:   "list topics"}
 
@@ -141,4 +375,8 @@ func init() {
 	flags.BoolVarP(&runner.shouldShowCompactedOnly, "compact", "c", false, "show compacted topics only")
 	flags.BoolVarP(&runner.shouldShowInfiniteOnly, "infinite", "i", false, "show infinite topics only")
 	flags.BoolVarP(&runner.verbose, "verbose", "v", false, "print more info (configs)")
+	runner.selector.addFlags(flags)
+	flags.StringVarP(&runner.output, "output", "o", "text", "output format: text, json, yaml, csv, table or wide")
+	flags.StringSliceVar(&runner.columns, "columns", nil, "columns for table and csv output: name, partitions, writable, leaders, messages, size or any config key")
+	flags.BoolVar(&runner.shouldShowSize, "size", false, "fetch topic sizes from broker log dirs")
 }
@@ -3,10 +3,12 @@ package main
 import (
 	"errors"
 	"fmt"
-	"github.com/Shopify/sarama"
+	"github.com/IBM/sarama"
 	"github.com/spf13/cobra"
 	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -29,6 +31,7 @@ type topicModCmdType struct {
 	client            sarama.Client
 	partitions        int32
 	replicationFactor int16
+	newReplicas       int
 	shouldDelete      bool
 	configName        string
 	configKey         string
@@ -37,12 +40,22 @@ type topicModCmdType struct {
 	segmentDuration   string
 	retentionSize     string
 	retentionDuration string
+	isDryRun          bool
+	guard             destructiveGuard
+	selector          topicSelector
+	unsetKeys         []string
+	appendPairs       []string
+	subtractPairs     []string
+	assignment        string
+	replicaAssignment [][]int32
+	isRackAware       bool
+	brokers           []int
 }
 
-func (t *topicModCmdType) makeConfig() map[string]string {
-	result := make(map[string]string)
-	for key, value := range topicConfigPresets[t.configName] {
-		result[key] = value
+func (t *topicModCmdType) makeConfig() (map[string]string, error) {
+	result, err := composePresets(t.configName)
+	if err != nil {
+		return nil, err
 	}
 	if len(t.configKey) > 0 {
 		result[t.configKey] = t.configValue
@@ -78,11 +91,206 @@ func (t *topicModCmdType) makeConfig() map[string]string {
 			fmt.Println("retention duration conversion error:", err)
 		}
 	}
+	return result, nil
+}
+
+func (t *topicModCmdType) makeChanges(config map[string]string) (kafkaadmin.ConfigChanges, error) {
+	result := kafkaadmin.SetConfigChanges(config, t.unsetKeys)
+	operations := map[sarama.IncrementalAlterConfigsOperation][]string{
+		sarama.IncrementalAlterConfigsOperationAppend:   t.appendPairs,
+		sarama.IncrementalAlterConfigsOperationSubtract: t.subtractPairs}
+	for operation, pairs := range operations {
+		for _, pair := range pairs {
+			key, value, err := parseKeyValue(pair)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = sarama.IncrementalAlterConfigsEntry{Operation: operation, Value: &value}
+		}
+	}
+	return result, nil
+}
+
+func validationErrors(err error) (kafkaadmin.TopicErrors, error) {
+	if err == nil {
+		return nil, nil
+	}
+	var topicErrors kafkaadmin.TopicErrors
+	if !errors.As(err, &topicErrors) {
+		return nil, err
+	}
+	return topicErrors, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	var result []string
+	for key := range values {
+		result = append(result, key)
+	}
+	sort.Strings(result)
 	return result
 }
 
+func printConfigDiff(indent string, current kafkaadmin.RawConfig, changes kafkaadmin.ConfigChanges) {
+	before := configValues(current)
+	after := kafkaadmin.EffectiveConfigs(current, sarama.SourceTopic, changes)
+	for _, key := range sortedKeys(after) {
+		if before[key] != after[key] {
+			fmt.Printf("%s%s: %s -> %s\n", indent, key, before[key], after[key])
+		}
+	}
+}
+
+func (t *topicModCmdType) runDryRun(topics []string, config map[string]string, changes kafkaadmin.ConfigChanges) error {
+	allTopics, err := t.client.Topics()
+	if err != nil {
+		return err
+	}
+	var newTopics, existingTopics []string
+	for _, topic := range topics {
+		if inArray(topic, allTopics) {
+			existingTopics = append(existingTopics, topic)
+		} else {
+			newTopics = append(newTopics, topic)
+		}
+	}
+	if len(newTopics) > 0 {
+		createErrors, err := validationErrors(t.createTopics(newTopics, config, true))
+		if err != nil {
+			return err
+		}
+		var assignments map[string][][]int32
+		if t.isAssigned() {
+			assignments, err = t.assign(newTopics, nil)
+			if err != nil {
+				return err
+			}
+		}
+		for _, topic := range newTopics {
+			fmt.Printf("%s: would be created with %d partitions, replication factor %d\n", topic, t.partitions, t.replicationFactor)
+			if assignment, ok := assignments[topic]; ok {
+				fmt.Printf("\tassignment: %s\n", formatReplicaAssignment(assignment))
+			}
+			for _, key := range sortedKeys(config) {
+				fmt.Printf("\t%s=%s\n", key, config[key])
+			}
+			if err, ok := createErrors[topic]; ok {
+				fmt.Println("\tvalidation failed:", err)
+			}
+		}
+	}
+	if len(existingTopics) == 0 {
+		return nil
+	}
+	configs, err := kafkaadmin.GetTopicConfigs(t.client, existingTopics...)
+	if err != nil {
+		return err
+	}
+	var resizedTopics []string
+	currentPartitions := make(map[string]int)
+	for _, topic := range existingTopics {
+		partitions, err := t.client.Partitions(topic)
+		if err != nil {
+			return err
+		}
+		currentPartitions[topic] = len(partitions)
+		if int32(len(partitions)) != t.partitions {
+			resizedTopics = append(resizedTopics, topic)
+		}
+	}
+	var partitionErrors, configErrors kafkaadmin.TopicErrors
+	if len(resizedTopics) > 0 {
+		partitionErrors, err = validationErrors(t.createPartitions(resizedTopics, true))
+		if err != nil {
+			return err
+		}
+	}
+	if len(changes) > 0 {
+		configErrors, err = validationErrors(kafkaadmin.ModifyTopicConfig(t.client, changes, true, existingTopics...))
+		if err != nil {
+			return err
+		}
+	}
+	for _, topic := range existingTopics {
+		fmt.Printf("%s: exists\n", topic)
+		if inArray(topic, resizedTopics) {
+			fmt.Printf("\tpartitions: %d -> %d\n", currentPartitions[topic], t.partitions)
+			if err, ok := partitionErrors[topic]; ok {
+				fmt.Println("\tvalidation failed:", err)
+			}
+		} else {
+			fmt.Printf("\tpartitions: %d (unchanged)\n", currentPartitions[topic])
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Println("\tconfigs:")
+		printConfigDiff("\t\t", configs[topic], changes)
+		if err, ok := configErrors[topic]; ok {
+			fmt.Println("\tvalidation failed:", err)
+		}
+	}
+	return t.changeReplicationFactor(existingTopics, false)
+}
+
+func (t *topicModCmdType) changeReplicationFactor(topics []string, shouldExecute bool) error {
+	if t.newReplicas <= 0 || len(topics) == 0 {
+		return nil
+	}
+	current, proposed, err := proposeReassignment(t.client, topics, nil, t.newReplicas, true)
+	if err != nil {
+		return err
+	}
+	changes := changedAssignment(current, proposed)
+	for topic, partitions := range changes {
+		for partition := range partitions {
+			if len(current[topic][partition]) == t.newReplicas {
+				delete(partitions, partition)
+			}
+		}
+		if len(partitions) == 0 {
+			delete(changes, topic)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	fmt.Printf("replication factor %d:\n", t.newReplicas)
+	printReassignment(current, changes)
+	if !shouldExecute {
+		return nil
+	}
+	return kafkaadmin.AlterReassignments(t.client, changes)
+}
+
 func (t *topicModCmdType) Run(cmd *cobra.Command, topics []string) error {
-	var err error
+	if t.newReplicas > 0 {
+		t.replicationFactor = int16(t.newReplicas)
+	}
+	if len(t.assignment) > 0 {
+		var err error
+		t.replicaAssignment, err = parseReplicaAssignment(t.assignment)
+		if err != nil {
+			return err
+		}
+		t.partitions = int32(len(t.replicaAssignment))
+		t.replicationFactor = int16(len(t.replicaAssignment[0]))
+	}
+	if !t.selector.isPositive(topics) {
+		return fmt.Errorf("topics, glob patterns, --regex or --where expected, --exclude or --include-internal alone would select every topic")
+	}
+	config, err := t.makeConfig()
+	if err != nil {
+		return err
+	}
+	changes, err := t.makeChanges(config)
+	if err != nil {
+		return err
+	}
+	for _, pair := range t.appendPairs {
+		key, value, _ := parseKeyValue(pair)
+		config[key] = value
+	}
 	t.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
 	if err != nil {
 		return err
@@ -92,12 +300,159 @@ func (t *topicModCmdType) Run(cmd *cobra.Command, topics []string) error {
 	if err != nil {
 		return err
 	}
+	if t.selector.isActive(topics) {
+		topics, _, err = t.selector.Select(t.client, topics)
+		if err != nil {
+			return err
+		}
+		if len(topics) == 0 {
+			fmt.Println("no topics selected")
+			return nil
+		}
+	}
 	if t.shouldDelete {
-		return kafkaadmin.DeleteTopics(t.client, topics...)
+		if t.isDryRun {
+			for _, topic := range topics {
+				fmt.Printf("%s: would be deleted\n", topic)
+			}
+			return nil
+		}
+		err = t.guard.check(t.client, "deleted", topics, true)
+		if err != nil {
+			return err
+		}
+		deleteAudit := newAudit(t.client)
+		err = deleteAudit.track(auditTopic, topics...)
+		if err != nil {
+			return err
+		}
+		deleteAudit.deleting(topics...)
+		return deleteAudit.finish(kafkaadmin.DeleteTopics(t.client, topics...))
+	}
+
+	if t.isDryRun {
+		return t.runDryRun(topics, config, changes)
+	}
+	modAudit := newAudit(t.client)
+	err = modAudit.track(auditTopic, topics...)
+	if err != nil {
+		return err
+	}
+	return modAudit.finish(t.modify(topics, config, changes))
+}
+
+func (t *topicModCmdType) isAssigned() bool {
+	return len(t.replicaAssignment) > 0 || t.isRackAware || len(t.brokers) > 0
+}
+
+func (t *topicModCmdType) assign(topics []string, current map[string]int) (map[string][][]int32, error) {
+	result := make(map[string][][]int32)
+	if len(t.replicaAssignment) > 0 {
+		for _, topic := range topics {
+			if current[topic] < len(t.replicaAssignment) {
+				result[topic] = t.replicaAssignment[current[topic]:]
+			}
+		}
+		return result, nil
+	}
+	brokers, err := brokerSubset(t.client, t.brokers)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := kafkaadmin.ClusterAssignment(t.client)
+	if err != nil {
+		return nil, err
 	}
+	counts := make(map[string]int)
+	replicationFactors := make(map[string]int)
+	for _, topic := range topics {
+		if int(t.partitions) <= current[topic] {
+			continue
+		}
+		counts[topic] = int(t.partitions) - current[topic]
+		replicationFactors[topic] = int(t.replicationFactor)
+		if replicas, ok := existing[topic][0]; ok {
+			replicationFactors[topic] = len(replicas)
+		}
+	}
+	return kafkaadmin.NewAssignments(brokers, existing, counts, replicationFactors, t.isRackAware)
+}
 
-	config := t.makeConfig()
-	err = kafkaadmin.CreateTopics(t.client, t.partitions, t.replicationFactor, config, topics...)
+func (t *topicModCmdType) createTopics(topics []string, config map[string]string, validateOnly bool) error {
+	if !t.isAssigned() {
+		return kafkaadmin.CreateTopics(t.client, t.partitions, t.replicationFactor, config, validateOnly, topics...)
+	}
+	allTopics, err := t.client.Topics()
+	if err != nil {
+		return err
+	}
+	topicErrors := make(kafkaadmin.TopicErrors)
+	var newTopics []string
+	for _, topic := range topics {
+		if inArray(topic, allTopics) {
+			topicErrors[topic] = sarama.ErrTopicAlreadyExists
+		} else {
+			newTopics = append(newTopics, topic)
+		}
+	}
+	if len(newTopics) > 0 {
+		assignments, err := t.assign(newTopics, nil)
+		if err != nil {
+			return err
+		}
+		err = kafkaadmin.CreateAssignedTopics(t.client, assignments, config, validateOnly)
+		var createErrors kafkaadmin.TopicErrors
+		if errors.As(err, &createErrors) {
+			for topic, err := range createErrors {
+				topicErrors[topic] = err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(topicErrors) > 0 {
+		return topicErrors
+	}
+	return nil
+}
+
+func (t *topicModCmdType) createPartitions(topics []string, validateOnly bool) error {
+	if !t.isAssigned() {
+		return kafkaadmin.CreatePartitions(t.client, t.partitions, validateOnly, topics...)
+	}
+	current := make(map[string]int)
+	for _, topic := range topics {
+		partitions, err := t.client.Partitions(topic)
+		if err != nil {
+			return err
+		}
+		current[topic] = len(partitions)
+	}
+	assignments, err := t.assign(topics, current)
+	if err != nil {
+		return err
+	}
+	topicPartitions := make(map[string]*sarama.TopicPartition)
+	for _, topic := range topics {
+		topicPartitions[topic] = &sarama.TopicPartition{Count: t.partitions, Assignment: assignments[topic]}
+	}
+	return kafkaadmin.CreateTopicPartitions(t.client, topicPartitions, validateOnly)
+}
+
+func formatReplicaAssignment(assignment [][]int32) string {
+	var partitions []string
+	for _, replicas := range assignment {
+		var ids []string
+		for _, replica := range replicas {
+			ids = append(ids, strconv.Itoa(int(replica)))
+		}
+		partitions = append(partitions, strings.Join(ids, ":"))
+	}
+	return strings.Join(partitions, ",")
+}
+
+func (t *topicModCmdType) modify(topics []string, config map[string]string, changes kafkaadmin.ConfigChanges) error {
+	err := t.createTopics(topics, config, false)
 	if err == nil {
 		return nil
 	}
@@ -112,7 +467,7 @@ func (t *topicModCmdType) Run(cmd *cobra.Command, topics []string) error {
 		}
 		existingTopics = append(existingTopics, topic)
 	}
-	err = kafkaadmin.CreatePartitions(t.client, t.partitions, existingTopics...)
+	err = t.createPartitions(existingTopics, false)
 	if err != nil {
 		if !errors.As(err, &topicErrors) {
 			return err
@@ -120,10 +475,14 @@ func (t *topicModCmdType) Run(cmd *cobra.Command, topics []string) error {
 		fmt.Println("Errors while updating the number of partitions")
 		fmt.Println(topicErrors)
 	}
-	if len(config) == 0 {
+	err = t.changeReplicationFactor(existingTopics, true)
+	if err != nil {
+		fmt.Println("Error while changing the replication factor:", err)
+	}
+	if len(changes) == 0 {
 		return nil
 	}
-	err = kafkaadmin.ModifyTopicConfig(t.client, config, existingTopics...)
+	err = kafkaadmin.ModifyTopicConfig(t.client, changes, false, existingTopics...)
 	if err != nil {
 		if !errors.As(err, &topicErrors) {
 			return err
@@ -135,7 +494,7 @@ func (t *topicModCmdType) Run(cmd *cobra.Command, topics []string) error {
 }
 
 var topicModCmd = &cobra.Command{
-	Use:     "mod",
+	Use:     "mod [topics or glob patterns]",
 	Aliases: []string{"m"},
 	Short:   "modyfy or create topics"}
 
@@ -145,12 +504,22 @@ func init() {
 	flags := topicModCmd.Flags()
 	flags.Int32VarP(&runner.partitions, "partitions", "p", 1, "the number of partitions for topics")
 	flags.Int16VarP(&runner.replicationFactor, "replicas", "r", 1, "replication factor")
+	flags.IntVar(&runner.newReplicas, "replication-factor", 0, "replication factor, existing topics are reassigned (see partition status)")
+	flags.StringVar(&runner.assignment, "assignment", "", "replicas of every partition like 0:1:2,1:2:0 (sets partitions and replication factor)")
+	flags.BoolVar(&runner.isRackAware, "rack-aware", false, "place replicas of new partitions on the least loaded brokers of different racks instead of leaving it to the controller")
+	flags.IntSliceVar(&runner.brokers, "brokers", nil, "brokers to place replicas of new partitions on, the least loaded first")
 	flags.BoolVarP(&runner.shouldDelete, "delete", "d", false, "delete specified partitions (other parameters have no effect if this option is selected)")
-	flags.StringVarP(&runner.configName, "config", "c", "", "configuration profile name (compact and infinite hardcoded)")
+	flags.StringVarP(&runner.configName, "config", "c", "", "comma-separated config presets applied in order (see topic presets)")
 	flags.StringVarP(&runner.configKey, "key", "k", "", "topic config key to modify")
 	flags.StringVarP(&runner.configValue, "value", "v", "", "topic config value to modify")
 	flags.StringVar(&runner.segmentSize, "segment-size", "", "maximum segment size in bytes, suffixes K,M,G,T supported")
 	flags.StringVar(&runner.segmentDuration, "segment-duration", "", "maximum segment duration in format like 1w3d2h4m5s3ms (one week, 3 days,2 hours, 4 minutes, 5 seconds and 3 milliseconds, fractional numbers are not supported)")
 	flags.StringVar(&runner.retentionSize, "retention-size", "", "retention size in bytes, suffixes K,M,G,T supported")
 	flags.StringVar(&runner.retentionDuration, "retention-duration", "", "maximum retention duration, see segment-duration for format")
+	runner.selector.addFlags(flags)
+	runner.guard.addFlags(flags)
+	flags.BoolVar(&runner.isDryRun, "dry-run", false, "show what would change and validate it on the broker without applying")
+	flags.StringSliceVar(&runner.unsetKeys, "unset", nil, "topic config keys to revert to their defaults")
+	flags.StringArrayVar(&runner.appendPairs, "append", nil, "key=value, add values to a list config (like cleanup.policy)")
+	flags.StringArrayVar(&runner.subtractPairs, "subtract", nil, "key=value, remove values from a list config")
 }
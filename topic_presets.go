@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+type topicPreset struct {
+	source string
+	config map[string]string
+}
+
+var presetFileName string
+
+func loadTopicPresets() (map[string]*topicPreset, error) {
+	result := make(map[string]*topicPreset)
+	for name, config := range topicConfigPresets {
+		result[name] = &topicPreset{source: "builtin", config: config}
+	}
+	config, err := loadToolConfig()
+	if err != nil {
+		return nil, err
+	}
+	for name, presetConfig := range config.Presets {
+		result[name] = &topicPreset{source: "config file", config: presetConfig}
+	}
+	if len(presetFileName) > 0 {
+		presetFile, err := readToolConfig(presetFileName)
+		if err != nil {
+			return nil, err
+		}
+		for name, presetConfig := range presetFile.Presets {
+			result[name] = &topicPreset{source: presetFileName, config: presetConfig}
+		}
+	}
+	return result, nil
+}
+
+func composePresets(names string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(strings.TrimSpace(names)) == 0 {
+		return result, nil
+	}
+	presets, err := loadTopicPresets()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		preset, ok := presets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %s (see topic presets)", name)
+		}
+		for key, value := range preset.config {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func runTopicPresets(cmd *cobra.Command, args []string) error {
+	presets, err := loadTopicPresets()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		preset := presets[name]
+		fmt.Printf("%s (%s)\n", name, preset.source)
+		for _, key := range sortedKeys(preset.config) {
+			fmt.Printf("\t%s=%s\n", key, preset.config[key])
+		}
+	}
+	return nil
+}
+
+var topicPresetsCmd = &cobra.Command{
+	Use:   "presets",
+	Short: "list topic config presets",
+	RunE:  runTopicPresets}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComposePresets(t *testing.T) {
+	configFileName = filepath.Join(t.TempDir(), "config.yaml")
+	defer func() {
+		configFileName = ""
+	}()
+	err := os.WriteFile(configFileName, []byte("presets:\n  short:\n    retention.ms: \"3600000\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		names    string
+		expected map[string]string
+		isError  bool
+	}{
+		{names: "", expected: map[string]string{}},
+		{names: "compact", expected: map[string]string{"cleanup.policy": "compact"}},
+		{names: "compact,", expected: map[string]string{"cleanup.policy": "compact"}},
+		{names: " ,compact, ,short", expected: map[string]string{"cleanup.policy": "compact", "retention.ms": "3600000"}},
+		{names: "infinite,compact", expected: map[string]string{"cleanup.policy": "compact", "retention.ms": "-1", "retention.bytes": "-1"}},
+		{names: "compact,missing", isError: true},
+	} {
+		result, err := composePresets(test.names)
+		if test.isError {
+			if err == nil {
+				t.Errorf("%q: error expected, got %v", test.names, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.names, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%q: got %v, expected %v", test.names, result, test.expected)
+		}
+	}
+}
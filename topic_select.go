@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/pflag"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type topicPredicate struct {
+	key      string
+	operator string
+	value    string
+}
+
+var predicatePattern = regexp.MustCompile(`^\s*([^<>=!\s]+)\s*(>=|<=|!=|==|=|>|<)\s*(.*?)\s*$`)
+
+func parsePredicate(value string) (*topicPredicate, error) {
+	match := predicatePattern.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("invalid condition %s, expected key<op>value with op one of = != > < >= <=", value)
+	}
+	return &topicPredicate{key: match[1], operator: match[2], value: match[3]}, nil
+}
+
+func parseNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		return number, true
+	}
+	size, err := parseBinarySize(value)
+	if err == nil {
+		return float64(size), true
+	}
+	return 0, false
+}
+
+func (p *topicPredicate) isConfigKey() bool {
+	return p.key != "partitions" && p.key != "replicas"
+}
+
+func (p *topicPredicate) match(actual string) bool {
+	left, isLeftNumber := parseNumber(actual)
+	right, isRightNumber := parseNumber(p.value)
+	var comparison int
+	if isLeftNumber && isRightNumber {
+		switch {
+		case left < right:
+			comparison = -1
+		case left > right:
+			comparison = 1
+		}
+	} else {
+		comparison = strings.Compare(actual, p.value)
+	}
+	switch p.operator {
+	case "=", "==":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case ">":
+		return comparison > 0
+	case "<":
+		return comparison < 0
+	case ">=":
+		return comparison >= 0
+	case "<=":
+		return comparison <= 0
+	}
+	return false
+}
+
+func (p *topicPredicate) evaluate(topic *sarama.TopicMetadata, configs kafkaadmin.RawConfig) bool {
+	switch p.key {
+	case "partitions":
+		return p.match(strconv.Itoa(len(topic.Partitions)))
+	case "replicas":
+		replicas := 0
+		if len(topic.Partitions) > 0 {
+			replicas = len(topic.Partitions[0].Replicas)
+		}
+		return p.match(strconv.Itoa(replicas))
+	}
+	config, ok := configs[p.key]
+	if !ok {
+		return false
+	}
+	return p.match(config.Value)
+}
+
+type topicSelector struct {
+	regex           string
+	excludes        []string
+	includeInternal bool
+	conditions      []string
+}
+
+func (s *topicSelector) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&s.regex, "regex", "", "select topics matching the regular expression")
+	flags.StringArrayVar(&s.excludes, "exclude", nil, "skip topics matching the glob pattern")
+	flags.BoolVar(&s.includeInternal, "include-internal", false, "select internal topics (like __consumer_offsets) too")
+	flags.StringArrayVar(&s.conditions, "where", nil, "select topics by condition on a config or partitions/replicas, like retention.ms>604800000 or partitions<6")
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func (s *topicSelector) isActive(args []string) bool {
+	if len(s.regex) > 0 || len(s.excludes) > 0 || len(s.conditions) > 0 {
+		return true
+	}
+	for _, arg := range args {
+		if isGlob(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPositive reports whether something selects topics rather than only skipping them.
+func (s *topicSelector) isPositive(args []string) bool {
+	return len(s.regex) > 0 || len(s.conditions) > 0 || len(args) > 0
+}
+
+func (s *topicSelector) matchesArgs(topic string, args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	for _, arg := range args {
+		if arg == topic {
+			return true
+		}
+		if isGlob(arg) {
+			if matched, _ := path.Match(arg, topic); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *topicSelector) Select(client sarama.Client, args []string) ([]string, map[string]*sarama.TopicMetadata, error) {
+	var regex *regexp.Regexp
+	var err error
+	if len(s.regex) > 0 {
+		regex, err = regexp.Compile(s.regex)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	var predicates []*topicPredicate
+	needConfigs := false
+	for _, condition := range s.conditions {
+		predicate, err := parsePredicate(condition)
+		if err != nil {
+			return nil, nil, err
+		}
+		predicates = append(predicates, predicate)
+		needConfigs = needConfigs || predicate.isConfigKey()
+	}
+	metadata, err := kafkaadmin.GetMetadata(client)
+	if err != nil {
+		return nil, nil, err
+	}
+	topics := make(map[string]*sarama.TopicMetadata)
+	var names []string
+	for _, topic := range metadata.Topics {
+		if topic.Err != sarama.ErrNoError {
+			continue
+		}
+		if !s.matchesArgs(topic.Name, args) {
+			continue
+		}
+		if isInternalTopic(topic) && !s.includeInternal && !inArray(topic.Name, args) {
+			continue
+		}
+		if regex != nil && !regex.MatchString(topic.Name) {
+			continue
+		}
+		excluded := false
+		for _, exclude := range s.excludes {
+			if matched, _ := path.Match(exclude, topic.Name); matched {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		topics[topic.Name] = topic
+		names = append(names, topic.Name)
+	}
+	if len(predicates) > 0 && len(names) > 0 {
+		var configs kafkaadmin.TopicsConfigs
+		if needConfigs {
+			configs, err = kafkaadmin.GetTopicConfigs(client, names...)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		names = filterTopics(names, func(topic string) bool {
+			for _, predicate := range predicates {
+				if !predicate.evaluate(topics[topic], configs[topic]) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	sort.Strings(names)
+	return names, topics, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParsePredicate(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected topicPredicate
+		isError  bool
+	}{
+		{value: "partitions<6", expected: topicPredicate{key: "partitions", operator: "<", value: "6"}},
+		{value: "retention.ms>604800000", expected: topicPredicate{key: "retention.ms", operator: ">", value: "604800000"}},
+		{value: " cleanup.policy = compact ", expected: topicPredicate{key: "cleanup.policy", operator: "=", value: "compact"}},
+		{value: "replicas==3", expected: topicPredicate{key: "replicas", operator: "==", value: "3"}},
+		{value: "replicas!=3", expected: topicPredicate{key: "replicas", operator: "!=", value: "3"}},
+		{value: "segment.bytes>=1G", expected: topicPredicate{key: "segment.bytes", operator: ">=", value: "1G"}},
+		{value: "segment.bytes<=1G", expected: topicPredicate{key: "segment.bytes", operator: "<=", value: "1G"}},
+		{value: "message.format.version=", expected: topicPredicate{key: "message.format.version", operator: "=", value: ""}},
+		{value: "partitions", isError: true},
+		{value: "=compact", isError: true},
+		{value: "", isError: true},
+		{value: "cleanup policy=compact", isError: true},
+	} {
+		result, err := parsePredicate(test.value)
+		if test.isError {
+			if err == nil {
+				t.Errorf("%q: error expected, got %+v", test.value, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		if *result != test.expected {
+			t.Errorf("%q: got %+v, expected %+v", test.value, *result, test.expected)
+		}
+	}
+}
+
+func TestPredicateMatch(t *testing.T) {
+	for _, test := range []struct {
+		condition string
+		actual    string
+		expected  bool
+	}{
+		{condition: "partitions<6", actual: "3", expected: true},
+		{condition: "partitions<6", actual: "12", expected: false},
+		{condition: "retention.ms>=604800000", actual: "604800000", expected: true},
+		{condition: "segment.bytes>512M", actual: "1073741824", expected: true},
+		{condition: "replicas!=3", actual: "3", expected: false},
+		{condition: "cleanup.policy=compact", actual: "compact", expected: true},
+		{condition: "cleanup.policy==compact", actual: "delete", expected: false},
+		{condition: "compression.type>lz4", actual: "zstd", expected: true},
+	} {
+		predicate, err := parsePredicate(test.condition)
+		if err != nil {
+			t.Errorf("%s: %v", test.condition, err)
+			continue
+		}
+		if result := predicate.match(test.actual); result != test.expected {
+			t.Errorf("%s with %s: got %v, expected %v", test.condition, test.actual, result, test.expected)
+		}
+	}
+}
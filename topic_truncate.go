@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"sort"
+)
+
+type topicTruncateCmdType struct {
+	client     sarama.Client
+	partitions []int
+	offset     int64
+	timestamp  string
+	guard      destructiveGuard
+}
+
+func (t *topicTruncateCmdType) targetOffset(cmd *cobra.Command, topic string, partition int32, offsetRange kafkaadmin.OffsetRange, timestamp int64) (int64, error) {
+	target := offsetRange.Newest
+	switch {
+	case cmd.Flags().Changed("offset"):
+		target = t.offset
+	case len(t.timestamp) > 0:
+		offset, err := t.client.GetOffset(topic, partition, timestamp)
+		if err != nil {
+			return 0, err
+		}
+		if offset >= 0 {
+			target = offset
+		}
+	}
+	if target > offsetRange.Newest {
+		target = offsetRange.Newest
+	}
+	if target < offsetRange.Oldest {
+		target = offsetRange.Oldest
+	}
+	return target, nil
+}
+
+func (t *topicTruncateCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("exactly one topic expected")
+	}
+	if cmd.Flags().Changed("offset") && len(t.timestamp) > 0 {
+		return fmt.Errorf("--offset and --timestamp cannot be used together")
+	}
+	var timestamp int64
+	var err error
+	if len(t.timestamp) > 0 {
+		timestamp, err = parseTimestamp(t.timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	topic := args[0]
+	t.client, err = kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer t.client.Close()
+	existing, err := t.client.Partitions(topic)
+	if err != nil {
+		return err
+	}
+	partitions := existing
+	if len(t.partitions) > 0 {
+		partitions = nil
+		for _, partition := range t.partitions {
+			if !inInt32Array(int32(partition), existing) {
+				return fmt.Errorf("topic %s has no partition %d", topic, partition)
+			}
+			partitions = append(partitions, int32(partition))
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i] < partitions[j]
+	})
+	ranges, err := kafkaadmin.GetTopicRanges(t.client, kafkaadmin.TopicPartitions{topic: partitions}, nil)
+	if err != nil {
+		return err
+	}
+	offsets := make(map[int32]int64)
+	var total int64
+	for _, partition := range partitions {
+		offsetRange := ranges[topic][partition]
+		target, err := t.targetOffset(cmd, topic, partition, offsetRange, timestamp)
+		if err != nil {
+			return err
+		}
+		if target <= offsetRange.Oldest {
+			continue
+		}
+		offsets[partition] = target
+		total += target - offsetRange.Oldest
+		fmt.Printf("\t%d: %d-%d -> %d-%d, %d messages\n", partition, offsetRange.Oldest, offsetRange.Newest, target, offsetRange.Newest, target-offsetRange.Oldest)
+	}
+	if len(offsets) == 0 {
+		fmt.Println("nothing to delete")
+		return nil
+	}
+	fmt.Printf("%d messages in %d partitions\n", total, len(offsets))
+	err = t.guard.check(t.client, "truncated", []string{topic}, false)
+	if err != nil {
+		return err
+	}
+	truncateAudit := newAudit(t.client)
+	err = truncateAudit.track(auditTopic, topic)
+	if err != nil {
+		return err
+	}
+	watermarks, err := kafkaadmin.DeleteRecords(t.client, map[string]map[int32]int64{topic: offsets})
+	truncateAudit.finish(err)
+	var partitionErrors kafkaadmin.PartitionErrors
+	if err != nil && !errors.As(err, &partitionErrors) {
+		return err
+	}
+	for _, partition := range partitions {
+		if watermark, ok := watermarks[topic][partition]; ok {
+			fmt.Printf("\t%d: starts at %d\n", partition, watermark)
+		}
+	}
+	return err
+}
+
+func inInt32Array(target int32, array []int32) bool {
+	for _, value := range array {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+var topicTruncateCmd = &cobra.Command{
+	Use:   "truncate <topic>",
+	Short: "delete messages from the beginning of topic partitions (DeleteRecords)",
+	Long: "Deletes messages up to the high-water mark by default, up to --offset or up to the first message " +
+		"at or after --timestamp. The topic itself, its configs and ACLs are kept."}
+
+func init() {
+	var runner topicTruncateCmdType
+	topicTruncateCmd.RunE = runner.Run
+	flags := topicTruncateCmd.Flags()
+	flags.IntSliceVarP(&runner.partitions, "partitions", "p", nil, "partitions to truncate (all by default)")
+	flags.Int64VarP(&runner.offset, "offset", "o", 0, "delete messages before this offset")
+	flags.StringVarP(&runner.timestamp, "timestamp", "t", "", "delete messages older than this timestamp (unix milliseconds or RFC3339)")
+	runner.guard.addFlags(flags)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+	"strconv"
+)
+
+type topicUsageCmdType struct {
+	selector       topicSelector
+	sortBy         string
+	shouldShowRaw  bool
+	showPartitions bool
+	showReplicas   bool
+}
+
+type usageRow struct {
+	key  string
+	size int64
+	row  []string
+}
+
+func (t *topicUsageCmdType) formatSize(size int64) string {
+	if t.shouldShowRaw {
+		return strconv.FormatInt(size, 10)
+	}
+	return formatBinarySize(size)
+}
+
+func (t *topicUsageCmdType) sortRows(rows []usageRow) [][]string {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if t.sortBy == "size" && rows[i].size != rows[j].size {
+			return rows[i].size > rows[j].size
+		}
+		return rows[i].key < rows[j].key
+	})
+	var result [][]string
+	for _, row := range rows {
+		result = append(result, row.row)
+	}
+	return result
+}
+
+func (t *topicUsageCmdType) Run(cmd *cobra.Command, args []string) error {
+	if t.sortBy != "size" && t.sortBy != "name" {
+		return fmt.Errorf("unsupported sort order %s", t.sortBy)
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	names, _, err := t.selector.Select(client, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("no topics selected")
+		return nil
+	}
+	logDirs, err := kafkaadmin.DescribeLogDirs(client, names...)
+	if err != nil {
+		return err
+	}
+	sizes := kafkaadmin.TopicSizes(logDirs)
+	diskSizes := make(map[string]int64)
+	replicaCounts := make(map[string]int)
+	partitionSizes := make(map[string]map[int32]int64)
+	var replicaRows []usageRow
+	for _, dir := range logDirs {
+		for _, replica := range dir.Replicas {
+			diskSizes[replica.Topic] += replica.Size
+			replicaCounts[replica.Topic]++
+			if partitionSizes[replica.Topic] == nil {
+				partitionSizes[replica.Topic] = make(map[int32]int64)
+			}
+			if !replica.IsFuture && replica.Size > partitionSizes[replica.Topic][replica.Partition] {
+				partitionSizes[replica.Topic][replica.Partition] = replica.Size
+			}
+			key := fmt.Sprintf("%s-%010d-%010d-%s", replica.Topic, replica.Partition, replica.Broker, replica.Path)
+			replicaRows = append(replicaRows, usageRow{key: key, size: replica.Size, row: []string{
+				replica.Topic,
+				strconv.Itoa(int(replica.Partition)),
+				strconv.Itoa(int(replica.Broker)),
+				replica.Path,
+				t.formatSize(replica.Size),
+				strconv.FormatInt(replica.OffsetLag, 10),
+				strconv.FormatBool(replica.IsFuture)}})
+		}
+	}
+	var topicRows []usageRow
+	for _, name := range names {
+		topicRows = append(topicRows, usageRow{key: name, size: sizes[name], row: []string{
+			name,
+			t.formatSize(sizes[name]),
+			t.formatSize(diskSizes[name]),
+			strconv.Itoa(replicaCounts[name])}})
+	}
+	err = writeTable(os.Stdout, []string{"topic", "size", "disk", "replicas"}, t.sortRows(topicRows))
+	if err != nil {
+		return err
+	}
+	if t.showPartitions {
+		var partitionRows []usageRow
+		for topic, partitions := range partitionSizes {
+			for partition, size := range partitions {
+				partitionRows = append(partitionRows, usageRow{key: fmt.Sprintf("%s-%010d", topic, partition), size: size, row: []string{
+					topic,
+					strconv.Itoa(int(partition)),
+					t.formatSize(size)}})
+			}
+		}
+		fmt.Println("")
+		err = writeTable(os.Stdout, []string{"topic", "partition", "size"}, t.sortRows(partitionRows))
+		if err != nil {
+			return err
+		}
+	}
+	if t.showReplicas {
+		fmt.Println("")
+		err = writeTable(os.Stdout, []string{"topic", "partition", "broker", "dir", "size", "lag", "future"}, t.sortRows(replicaRows))
+		if err != nil {
+			return err
+		}
+	}
+	brokerSizes := make(map[int32]int64)
+	var diskRows []usageRow
+	for _, dir := range logDirs {
+		brokerSizes[dir.Broker] += dir.Size()
+		status := "ok"
+		if dir.Err != 0 {
+			status = dir.Err.Error()
+		}
+		diskRows = append(diskRows, usageRow{key: fmt.Sprintf("%010d-%s", dir.Broker, dir.Path), size: dir.Size(), row: []string{
+			strconv.Itoa(int(dir.Broker)),
+			dir.Path,
+			t.formatSize(dir.Size()),
+			strconv.Itoa(len(dir.Replicas)),
+			status}})
+	}
+	var brokerRows []usageRow
+	for broker, size := range brokerSizes {
+		brokerRows = append(brokerRows, usageRow{key: fmt.Sprintf("%010d", broker), size: size, row: []string{
+			strconv.Itoa(int(broker)),
+			t.formatSize(size)}})
+	}
+	fmt.Println("")
+	err = writeTable(os.Stdout, []string{"broker", "size"}, t.sortRows(brokerRows))
+	if err != nil {
+		return err
+	}
+	fmt.Println("")
+	return writeTable(os.Stdout, []string{"broker", "dir", "size", "replicas", "status"}, t.sortRows(diskRows))
+}
+
+var topicUsageCmd = &cobra.Command{
+	Use:     "usage [topics or glob patterns]",
+	Aliases: []string{"u"},
+	Short:   "show disk usage of topics per partition, replica, broker and log dir",
+	Long: "Queries DescribeLogDirs on every broker. The size of a topic is the sum of its largest partition replicas, " +
+		"disk is the space taken by all replicas. Broker and log dir totals only count the selected topics."}
+
+func init() {
+	var runner topicUsageCmdType
+	topicUsageCmd.RunE = runner.Run
+	flags := topicUsageCmd.Flags()
+	flags.StringVarP(&runner.sortBy, "sort", "s", "size", "sort order: size or name")
+	flags.BoolVarP(&runner.shouldShowRaw, "bytes", "b", false, "print sizes in bytes")
+	flags.BoolVarP(&runner.showPartitions, "partitions", "p", false, "print size of every partition")
+	flags.BoolVarP(&runner.showReplicas, "replicas", "r", false, "print size, log dir and lag of every replica")
+	runner.selector.addFlags(flags)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"os"
+	"sort"
+)
+
+func (c *auditChange) restoreChanges() kafkaadmin.ConfigChanges {
+	set := make(map[string]string)
+	var unset []string
+	for _, key := range c.changedConfigs() {
+		if value, ok := c.Before.Configs[key]; ok {
+			set[key] = value
+		} else {
+			unset = append(unset, key)
+		}
+	}
+	return kafkaadmin.SetConfigChanges(set, unset)
+}
+
+type undoCmdType struct {
+	guard destructiveGuard
+}
+
+func (u *undoCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("audit record id expected")
+	}
+	records, err := readAuditRecords()
+	if err != nil {
+		return err
+	}
+	var record *auditRecord
+	for _, item := range records {
+		if item.ID == args[0] {
+			record = item
+		}
+	}
+	if record == nil {
+		return fmt.Errorf("audit record %s not found", args[0])
+	}
+	if record.Cluster != hostPort.String() && !u.guard.isForced {
+		return fmt.Errorf("record %s was made on %s, use -a %s or --force", record.ID, record.Cluster, record.Cluster)
+	}
+	if len(record.Changes) == 0 {
+		fmt.Println("nothing to undo")
+		return nil
+	}
+	for _, change := range record.Changes {
+		if len(change.Description) > 0 {
+			return fmt.Errorf("record %s: %s %s cannot be undone, undo only restores topics, broker and broker logger configs", record.ID, change.Resource, change.Name)
+		}
+		if change.IsUnknown {
+			return fmt.Errorf("record %s: the state of %s %s after the command is unknown, it cannot be undone", record.ID, change.Resource, change.Name)
+		}
+	}
+	client, err := kafkaadmin.NewDefaultClient([]string{hostPort.String()})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	names := make(map[string][]string)
+	for _, change := range record.Changes {
+		names[change.Resource] = append(names[change.Resource], change.Name)
+	}
+	var deletes []string
+	details := make(map[string]*sarama.TopicDetail)
+	for _, change := range record.Changes {
+		switch {
+		case change.Before == nil && change.After != nil:
+			deletes = append(deletes, change.Name)
+		case change.Before != nil && change.After == nil:
+			detail := &sarama.TopicDetail{
+				NumPartitions:     int32(change.Before.Partitions),
+				ReplicationFactor: int16(change.Before.ReplicationFactor),
+				ConfigEntries:     make(map[string]*string)}
+			for key, value := range change.Before.Configs {
+				pvalue := new(string)
+				*pvalue = value
+				detail.ConfigEntries[key] = pvalue
+			}
+			details[change.Name] = detail
+			fmt.Printf("%s will be recreated, its messages are lost\n", change.Name)
+		case change.Before != nil && change.After != nil && change.After.Partitions > change.Before.Partitions:
+			fmt.Printf("%s: the number of partitions cannot be decreased from %d to %d\n", change.Name, change.After.Partitions, change.Before.Partitions)
+		}
+	}
+	err = u.guard.check(client, "deleted", deletes, true)
+	if err != nil {
+		return err
+	}
+	undoAudit := newAudit(client)
+	for _, resource := range []string{auditTopic, auditBroker, auditBrokerLogger} {
+		if len(names[resource]) > 0 {
+			err = undoAudit.track(resource, names[resource]...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	undoAudit.deleting(deletes...)
+	return undoAudit.finish(u.undo(client, record, details, deletes))
+}
+
+func (u *undoCmdType) undo(client sarama.Client, record *auditRecord, details map[string]*sarama.TopicDetail, deletes []string) error {
+	if len(deletes) > 0 {
+		err := kafkaadmin.DeleteTopics(client, deletes...)
+		if err != nil {
+			return err
+		}
+	}
+	if len(details) > 0 {
+		err := kafkaadmin.CreateTopicDetails(client, details, false)
+		if err != nil {
+			return err
+		}
+	}
+	for _, change := range record.Changes {
+		if change.Before == nil || change.After == nil {
+			continue
+		}
+		changes := change.restoreChanges()
+		if len(changes) == 0 {
+			continue
+		}
+		err := kafkaadmin.AlterConfigs(client, auditResourceTypes[change.Resource], changes, false, change.Name)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", change.Resource, change.Name, err)
+		}
+		fmt.Printf("%s %s: configs restored\n", change.Resource, change.Name)
+	}
+	return nil
+}
+
+func printAuditChange(change *auditChange) {
+	name := change.Name
+	if len(name) == 0 {
+		name = "(default)"
+	}
+	switch {
+	case len(change.Description) > 0:
+		fmt.Printf("\t! %s %s: %s\n", change.Resource, name, change.Description)
+		return
+	case change.IsUnknown:
+		fmt.Printf("\t? %s %s (state after the command unknown)\n", change.Resource, name)
+		return
+	case change.Before == nil:
+		fmt.Printf("\t+ %s %s\n", change.Resource, name)
+		return
+	case change.After == nil:
+		fmt.Printf("\t- %s %s\n", change.Resource, name)
+		return
+	}
+	fmt.Printf("\t~ %s %s\n", change.Resource, name)
+	if change.Before.Partitions != change.After.Partitions {
+		fmt.Printf("\t\tpartitions: %d -> %d\n", change.Before.Partitions, change.After.Partitions)
+	}
+	keys := change.changedConfigs()
+	sort.Strings(keys)
+	for _, key := range keys {
+		before, ok := change.Before.Configs[key]
+		if !ok {
+			before = "(unset)"
+		}
+		after, ok := change.After.Configs[key]
+		if !ok {
+			after = "(unset)"
+		}
+		fmt.Printf("\t\t%s: %s -> %s\n", key, before, after)
+	}
+}
+
+type auditListCmdType struct {
+	count int
+}
+
+func (a *auditListCmdType) Run(cmd *cobra.Command, args []string) error {
+	records, err := readAuditRecords()
+	if os.IsNotExist(err) {
+		fmt.Println("no audit records")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 && a.count > 0 && len(records) > a.count {
+		records = records[len(records)-a.count:]
+	}
+	for _, record := range records {
+		if len(args) > 0 && !inArray(record.ID, args) {
+			continue
+		}
+		fmt.Printf("%s %s %s@%s %s: %s\n", record.ID, record.Time.Format("2006-01-02 15:04:05"), record.User, record.Host, record.Cluster, record.Command)
+		if len(record.Error) > 0 {
+			fmt.Printf("\terror: %s\n", record.Error)
+		}
+		for _, change := range record.Changes {
+			printAuditChange(change)
+		}
+	}
+	return nil
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo <id>",
+	Short: "restore configs and topics changed by an audited command (see audit)",
+	Long: "Restores topics, topic configs, broker configs and broker logger configs recorded by audit. Acl, quota, " +
+		"user, reassignment, leader election and log dir changes are recorded (marked with !) but cannot be undone."}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [ids]",
+	Short: "show the audit log of modifying commands"}
+
+func init() {
+	var undoRunner undoCmdType
+	undoCmd.RunE = undoRunner.Run
+	undoRunner.guard.addFlags(undoCmd.Flags())
+	var auditRunner auditListCmdType
+	auditCmd.RunE = auditRunner.Run
+	auditCmd.Flags().IntVarP(&auditRunner.count, "last", "n", 20, "number of latest records to show, 0 for all")
+}
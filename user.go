@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tvanomr/kafkatool/kafkaadmin"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// minScramIterations is the lowest number of iterations Kafka accepts.
+const minScramIterations = 4096
+
+type scramCredentialRecord struct {
+	Mechanism  string `json:"mechanism" yaml:"mechanism"`
+	Iterations int32  `json:"iterations" yaml:"iterations"`
+}
+
+type userRecord struct {
+	Name        string                  `json:"name" yaml:"name"`
+	Credentials []scramCredentialRecord `json:"credentials" yaml:"credentials"`
+	Acls        []*aclBinding           `json:"acls,omitempty" yaml:"acls,omitempty"`
+	Quotas      []*quotaRecord          `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+}
+
+func newUserRecord(user *kafkaadmin.ScramUser) *userRecord {
+	result := &userRecord{Name: user.Name}
+	for _, credential := range user.Credentials {
+		result.Credentials = append(result.Credentials, scramCredentialRecord{Mechanism: credential.Mechanism.String(), Iterations: credential.Iterations})
+	}
+	return result
+}
+
+func (u *userRecord) formatCredentials() string {
+	var result []string
+	for _, credential := range u.Credentials {
+		result = append(result, fmt.Sprintf("%s:%d", credential.Mechanism, credential.Iterations))
+	}
+	return strings.Join(result, ", ")
+}
+
+func newScramClient() (sarama.Client, error) {
+	client, err := kafkaadmin.NewVersionedClient([]string{hostPort.String()}, sarama.V2_7_0_0)
+	if err != nil {
+		return nil, err
+	}
+	err = kafkaadmin.RequireApis(client, kafkaadmin.DescribeUserScramCredentialsKey, kafkaadmin.AlterUserScramCredentialsKey)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func findScramUser(client sarama.Client, name string) (*kafkaadmin.ScramUser, error) {
+	users, err := kafkaadmin.DescribeScramUsers(client, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func readPassword(fileName string) ([]byte, error) {
+	var reader io.Reader = os.Stdin
+	if len(fileName) > 0 && fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	} else if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, "password: ")
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if len(password) == 0 {
+			return nil, fmt.Errorf("empty password")
+		}
+		return password, nil
+	}
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) == 0 {
+		return nil, fmt.Errorf("empty password")
+	}
+	return []byte(password), nil
+}
+
+type userCredentialFlags struct {
+	mechanisms   []string
+	iterations   int32
+	passwordFile string
+}
+
+func (u *userCredentialFlags) addFlags(flags *pflag.FlagSet, defaultMechanisms []string) {
+	flags.StringSliceVarP(&u.mechanisms, "mechanism", "m", defaultMechanisms, "SCRAM mechanisms: SCRAM-SHA-256, SCRAM-SHA-512")
+	flags.Int32VarP(&u.iterations, "iterations", "i", minScramIterations, "SCRAM iterations")
+	flags.StringVarP(&u.passwordFile, "password-file", "f", "", "file with the password (stdin if not set or -)")
+}
+
+func (u *userCredentialFlags) upsertions(name string, mechanisms []string, iterations int32) ([]sarama.AlterUserScramCredentialsUpsert, error) {
+	if iterations < minScramIterations {
+		return nil, fmt.Errorf("at least %d iterations expected", minScramIterations)
+	}
+	var parsed []sarama.ScramMechanismType
+	for _, value := range mechanisms {
+		mechanism, err := kafkaadmin.ParseScramMechanism(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, mechanism)
+	}
+	password, err := readPassword(u.passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	var result []sarama.AlterUserScramCredentialsUpsert
+	for _, mechanism := range parsed {
+		result = append(result, sarama.AlterUserScramCredentialsUpsert{Name: name, Mechanism: mechanism, Iterations: iterations, Password: password})
+	}
+	return result, nil
+}
+
+type userListCmdType struct {
+	output string
+}
+
+func (u *userListCmdType) Run(cmd *cobra.Command, args []string) error {
+	client, err := newScramClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	users, err := kafkaadmin.DescribeScramUsers(client, args...)
+	if err != nil {
+		return err
+	}
+	var records []*userRecord
+	var rows [][]string
+	for _, user := range users {
+		record := newUserRecord(user)
+		records = append(records, record)
+		rows = append(rows, []string{record.Name, record.formatCredentials()})
+	}
+	if u.output != "text" {
+		return writeStructured(os.Stdout, u.output, records)
+	}
+	return writeTable(os.Stdout, []string{"user", "credentials"}, rows)
+}
+
+type userDescribeCmdType struct {
+	output string
+}
+
+func (u *userDescribeCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("user name expected")
+	}
+	name := args[0]
+	client, err := newScramClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	user, err := findScramUser(client, name)
+	if err != nil {
+		return err
+	}
+	record := &userRecord{Name: name}
+	if user != nil {
+		record = newUserRecord(user)
+	}
+	filters, err := (&aclFlags{principal: "User:" + name}).filters()
+	if err != nil {
+		return err
+	}
+	record.Acls, err = describeAcls(client, filters)
+	if err != nil {
+		return err
+	}
+	quotas, err := kafkaadmin.DescribeClientQuotas(client, kafkaadmin.QuotaEntity{User: &name}, false)
+	if err != nil {
+		return err
+	}
+	if len(quotas) == 0 {
+		empty := ""
+		quotas, err = kafkaadmin.DescribeClientQuotas(client, kafkaadmin.QuotaEntity{User: &empty}, false)
+		if err != nil {
+			return err
+		}
+	}
+	for _, quota := range quotas {
+		record.Quotas = append(record.Quotas, &quotaRecord{User: quota.Entity.User, ClientID: quota.Entity.ClientID, Values: quota.Values})
+	}
+	if u.output != "text" {
+		return writeStructured(os.Stdout, u.output, record)
+	}
+	if user == nil {
+		fmt.Printf("User %s has no SCRAM credentials\n", name)
+	} else {
+		fmt.Printf("User %s: %s\n", name, record.formatCredentials())
+	}
+	fmt.Println("\nACLs:")
+	if len(record.Acls) == 0 {
+		fmt.Println("none")
+	} else {
+		err = writeAclBindings("text", record.Acls)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("\nQuotas:")
+	if len(quotas) == 0 {
+		fmt.Println("none")
+		return nil
+	}
+	return writeQuotas("text", quotas)
+}
+
+type userCreateCmdType struct {
+	credentials userCredentialFlags
+}
+
+func (u *userCreateCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("user name expected")
+	}
+	client, err := newScramClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	existing, err := findScramUser(client, args[0])
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("user %s already exists, use rotate to change the password", args[0])
+	}
+	upsertions, err := u.credentials.upsertions(args[0], u.credentials.mechanisms, u.credentials.iterations)
+	if err != nil {
+		return err
+	}
+	createAudit := newAudit(client)
+	createAudit.describe(auditUser, args[0], "created (%s)", strings.Join(u.credentials.mechanisms, ", "))
+	err = createAudit.finish(kafkaadmin.AlterScramCredentials(client, upsertions, nil))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("user %s created (%s)\n", args[0], strings.Join(u.credentials.mechanisms, ", "))
+	return nil
+}
+
+type userRotateCmdType struct {
+	credentials userCredentialFlags
+}
+
+func (u *userRotateCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("user name expected")
+	}
+	client, err := newScramClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	existing, err := findScramUser(client, args[0])
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("user %s does not exist", args[0])
+	}
+	mechanisms := u.credentials.mechanisms
+	iterations := u.credentials.iterations
+	if !cmd.Flags().Changed("mechanism") {
+		mechanisms = nil
+		for _, credential := range existing.Credentials {
+			mechanisms = append(mechanisms, credential.Mechanism.String())
+		}
+	}
+	if !cmd.Flags().Changed("iterations") {
+		for _, credential := range existing.Credentials {
+			if credential.Iterations > iterations {
+				iterations = credential.Iterations
+			}
+		}
+	}
+	upsertions, err := u.credentials.upsertions(args[0], mechanisms, iterations)
+	if err != nil {
+		return err
+	}
+	var kept []sarama.ScramMechanismType
+	for _, upsertion := range upsertions {
+		kept = append(kept, upsertion.Mechanism)
+	}
+	var deletions []sarama.AlterUserScramCredentialsDelete
+	for _, credential := range existing.Credentials {
+		if !containsScramMechanism(kept, credential.Mechanism) {
+			deletions = append(deletions, sarama.AlterUserScramCredentialsDelete{Name: args[0], Mechanism: credential.Mechanism})
+		}
+	}
+	rotateAudit := newAudit(client)
+	rotateAudit.describe(auditUser, args[0], "password rotated (%s, %d iterations)", strings.Join(mechanisms, ", "), iterations)
+	err = rotateAudit.finish(kafkaadmin.AlterScramCredentials(client, upsertions, deletions))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("password of %s rotated (%s, %d iterations)\n", args[0], strings.Join(mechanisms, ", "), iterations)
+	return nil
+}
+
+type userDeleteCmdType struct {
+	mechanisms  []string
+	isAssumeYes bool
+}
+
+func (u *userDeleteCmdType) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("user name expected")
+	}
+	var mechanisms []sarama.ScramMechanismType
+	for _, name := range u.mechanisms {
+		mechanism, err := kafkaadmin.ParseScramMechanism(name)
+		if err != nil {
+			return err
+		}
+		mechanisms = append(mechanisms, mechanism)
+	}
+	client, err := newScramClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	existing, err := findScramUser(client, args[0])
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("user %s does not exist", args[0])
+	}
+	var deletions []sarama.AlterUserScramCredentialsDelete
+	for _, credential := range existing.Credentials {
+		if len(mechanisms) == 0 || containsScramMechanism(mechanisms, credential.Mechanism) {
+			deletions = append(deletions, sarama.AlterUserScramCredentialsDelete{Name: args[0], Mechanism: credential.Mechanism})
+		}
+	}
+	if len(deletions) == 0 {
+		fmt.Printf("user %s has no such credentials\n", args[0])
+		return nil
+	}
+	if !u.isAssumeYes && !confirmTyped(fmt.Sprintf("%d credentials of user %s will be deleted", len(deletions), args[0]), args[0]) {
+		return fmt.Errorf("aborted")
+	}
+	deleteAudit := newAudit(client)
+	for _, deletion := range deletions {
+		deleteAudit.describe(auditUser, args[0], "%s credentials deleted", deletion.Mechanism)
+	}
+	err = deleteAudit.finish(kafkaadmin.AlterScramCredentials(client, nil, deletions))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d credentials of user %s deleted\n", len(deletions), args[0])
+	if len(deletions) == len(existing.Credentials) {
+		fmt.Printf("ACLs and quotas of User:%s are kept, see acl remove and quota delete\n", args[0])
+	}
+	return nil
+}
+
+func containsScramMechanism(mechanisms []sarama.ScramMechanismType, target sarama.ScramMechanismType) bool {
+	for _, mechanism := range mechanisms {
+		if mechanism == target {
+			return true
+		}
+	}
+	return false
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list [users]",
+	Short: "list users with SCRAM credentials"}
+
+var userDescribeCmd = &cobra.Command{
+	Use:   "describe <user>",
+	Short: "show SCRAM credentials, ACLs and quotas of a user"}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <user>",
+	Short: "create SCRAM credentials, the password is read from stdin or --password-file"}
+
+var userRotateCmd = &cobra.Command{
+	Use:   "rotate <user>",
+	Short: "change the password of an existing user"}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <user>",
+	Short: "delete SCRAM credentials of a user, all mechanisms by default"}
+
+func init() {
+	var listRunner userListCmdType
+	userListCmd.RunE = listRunner.Run
+	userListCmd.Flags().StringVarP(&listRunner.output, "output", "o", "text", "output format: text, json or yaml")
+	var describeRunner userDescribeCmdType
+	userDescribeCmd.RunE = describeRunner.Run
+	userDescribeCmd.Flags().StringVarP(&describeRunner.output, "output", "o", "text", "output format: text, json or yaml")
+	var createRunner userCreateCmdType
+	userCreateCmd.RunE = createRunner.Run
+	createRunner.credentials.addFlags(userCreateCmd.Flags(), []string{sarama.SASLTypeSCRAMSHA512})
+	var rotateRunner userRotateCmdType
+	userRotateCmd.RunE = rotateRunner.Run
+	rotateRunner.credentials.addFlags(userRotateCmd.Flags(), []string{sarama.SASLTypeSCRAMSHA512})
+	var deleteRunner userDeleteCmdType
+	userDeleteCmd.RunE = deleteRunner.Run
+	flags := userDeleteCmd.Flags()
+	flags.StringSliceVarP(&deleteRunner.mechanisms, "mechanism", "m", nil, "SCRAM mechanisms to delete (all by default)")
+	flags.BoolVarP(&deleteRunner.isAssumeYes, "yes", "y", false, "do not ask for confirmation")
+}